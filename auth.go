@@ -0,0 +1,498 @@
+package goscraper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+const (
+	AuthPOST   = "post"
+	AuthFORM   = "form"
+	AuthBASIC  = "basic"
+	AuthDIGEST = "digest"
+	AuthBEARER = "bearer"
+	AuthHEADER = "header"
+	AuthCOOKIE = "cookie"
+)
+
+// Authenticator logs the collector in. It is called before the crawl and
+// again whenever the crawler finds itself logged out, so it must be safe to
+// call more than once.
+type Authenticator interface {
+	Authenticate(c *colly.Collector) error
+}
+
+// PostAuthenticator posts Data to LoginURL as is.
+type PostAuthenticator struct {
+	LoginURL string
+	Data     map[string]string
+}
+
+func (a *PostAuthenticator) Authenticate(c *colly.Collector) error {
+	if err := c.Post(a.LoginURL, a.Data); err != nil {
+		return fmt.Errorf("failed to post login:%s:%v", a.LoginURL, err)
+	}
+	return nil
+}
+
+// FormAuthenticator gets PageURL first and posts Data together with the
+// hidden inputs of the login form, such as CSRF tokens, to LoginURL or to the
+// form action if LoginURL is empty. Both requests are made by the collector,
+// so the session cookies of the login page are kept as they are set.
+type FormAuthenticator struct {
+	PageURL      string
+	LoginURL     string
+	Data         map[string]string
+	FormSelector string
+}
+
+func (a *FormAuthenticator) Authenticate(c *colly.Collector) error {
+	pageURL := a.PageURL
+	if pageURL == "" {
+		pageURL = a.LoginURL
+	}
+	page := c.Clone()
+	page.Async = false
+	page.AllowURLRevisit = true
+	var res *colly.Response
+	page.OnResponse(func(r *colly.Response) {
+		res = r
+	})
+	if err := page.Visit(pageURL); err != nil {
+		return fmt.Errorf("failed to get login page:%s:%v", pageURL, err)
+	}
+	if res == nil {
+		return fmt.Errorf("failed to get login page:%s", pageURL)
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(res.Body))
+	if err != nil {
+		return fmt.Errorf("failed to parse login page:%s:%v", pageURL, err)
+	}
+
+	selector := a.FormSelector
+	if selector == "" {
+		selector = "form:has(input[type=password])"
+	}
+	form := doc.Find(selector).First()
+	if form.Length() == 0 {
+		return fmt.Errorf("not found login form:%s:%s", pageURL, selector)
+	}
+
+	loginURL := a.LoginURL
+	if loginURL == "" {
+		action, _ := form.Attr("action")
+		u, err := res.Request.URL.Parse(action)
+		if err != nil {
+			return fmt.Errorf("invalid login form action:%s:%v", action, err)
+		}
+		loginURL = u.String()
+	}
+
+	data := make(map[string]string)
+	form.Find("input[type=hidden]").Each(func(_ int, s *goquery.Selection) {
+		if name, ok := s.Attr("name"); ok {
+			data[name], _ = s.Attr("value")
+		}
+	})
+	for k, v := range a.Data {
+		data[k] = v
+	}
+
+	if err := c.Post(loginURL, data); err != nil {
+		return fmt.Errorf("failed to post login:%s:%v", loginURL, err)
+	}
+	return nil
+}
+
+// HeaderAuthenticator adds Headers to the requests to Hosts only, not to
+// leak credentials to other hosts. Hosts match the host of a request with or
+// without its port, and are set to the hosts of Entry and LoginURL by
+// NewLinkScraper if empty.
+type HeaderAuthenticator struct {
+	Headers map[string]string
+	Hosts   []string
+	once    sync.Once
+}
+
+func BearerAuthenticator(token string) *HeaderAuthenticator {
+	return &HeaderAuthenticator{
+		Headers: map[string]string{"Authorization": fmt.Sprintf("Bearer %s", token)},
+	}
+}
+
+func BasicAuthenticator(username, password string) *HeaderAuthenticator {
+	r := &http.Request{Header: make(http.Header)}
+	r.SetBasicAuth(username, password)
+	return &HeaderAuthenticator{
+		Headers: map[string]string{"Authorization": r.Header.Get("Authorization")},
+	}
+}
+
+func (a *HeaderAuthenticator) Authenticate(c *colly.Collector) error {
+	a.once.Do(func() {
+		c.OnRequest(func(r *colly.Request) {
+			if !a.allowed(r.URL) {
+				return
+			}
+			for k, v := range a.Headers {
+				r.Headers.Set(k, v)
+			}
+		})
+	})
+	return nil
+}
+
+func (a *HeaderAuthenticator) allowed(u *url.URL) bool {
+	return hostIn(u, a.Hosts)
+}
+
+// hostIn tells whether the host of u is one of hosts, with or without port.
+func hostIn(u *url.URL, hosts []string) bool {
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" && (h == strings.ToLower(u.Host) || h == strings.ToLower(u.Hostname())) {
+			return true
+		}
+	}
+	return false
+}
+
+// DigestAuthenticator answers HTTP Digest challenges (MD5, SHA-256 and their
+// -sess variants, qop=auth) by wrapping the collector transport. Challenges
+// are kept per host, and answered only for Hosts as HeaderAuthenticator.
+type DigestAuthenticator struct {
+	Username  string
+	Password  string
+	Hosts     []string
+	Transport http.RoundTripper
+	once      sync.Once
+}
+
+func (a *DigestAuthenticator) Authenticate(c *colly.Collector) error {
+	a.once.Do(func() {
+		base := a.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		c.WithTransport(&digestTransport{auth: a, base: base, challenges: make(map[string]*digestChallenge)})
+	})
+	return nil
+}
+
+type digestChallenge struct {
+	params map[string]string
+	nc     int
+}
+
+type digestTransport struct {
+	auth       *DigestAuthenticator
+	base       http.RoundTripper
+	mu         sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !hostIn(req.URL, t.auth.Hosts) {
+		return t.base.RoundTrip(req)
+	}
+	if header, ok := t.authorization(req); ok {
+		r := cloneRequest(req)
+		r.Header.Set("Authorization", header)
+		req = r
+	}
+	res, err := t.base.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	challenge, ok := parseDigestChallenge(res.Header.Get("WWW-Authenticate"))
+	if !ok || (req.Body != nil && req.GetBody == nil) {
+		return res, nil
+	}
+	t.mu.Lock()
+	t.challenges[req.URL.Host] = &digestChallenge{params: challenge}
+	t.mu.Unlock()
+	header, ok := t.authorization(req)
+	if !ok {
+		return res, nil
+	}
+	r := cloneRequest(req)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return res, nil
+		}
+		r.Body = body
+	}
+	r.Header.Set("Authorization", header)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	return t.base.RoundTrip(r)
+}
+
+func (t *digestTransport) authorization(req *http.Request) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.challenges[req.URL.Host]
+	if !ok {
+		return "", false
+	}
+	c.nc++
+	return digestAuthorization(c.params, t.auth.Username, t.auth.Password, req.Method, req.URL.RequestURI(), c.nc, newCnonce())
+}
+
+func cloneRequest(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, vs := range req.Header {
+		r.Header[k] = append([]string{}, vs...)
+	}
+	return r
+}
+
+func newCnonce() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func parseDigestChallenge(header string) (map[string]string, bool) {
+	if !strings.HasPrefix(strings.ToLower(header), "digest ") {
+		return nil, false
+	}
+	challenge := make(map[string]string)
+	rest := strings.TrimSpace(header[len("digest "):])
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+		challenge[key] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+		rest = strings.TrimSpace(rest)
+	}
+	_, ok := challenge["nonce"]
+	return challenge, ok
+}
+
+func digestAuthorization(challenge map[string]string, username, password, method, uri string, nc int, cnonce string) (string, bool) {
+	algorithm := challenge["algorithm"]
+	var h func() hash.Hash
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "", "MD5":
+		h = md5.New
+	case "SHA-256":
+		h = sha256.New
+	default:
+		return "", false
+	}
+	sum := func(s string) string {
+		d := h()
+		io.WriteString(d, s)
+		return hex.EncodeToString(d.Sum(nil))
+	}
+	realm := challenge["realm"]
+	nonce := challenge["nonce"]
+	ha1 := sum(fmt.Sprintf("%s:%s:%s", username, realm, password))
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = sum(fmt.Sprintf("%s:%s:%s", ha1, nonce, cnonce))
+	}
+	ha2 := sum(fmt.Sprintf("%s:%s", method, uri))
+
+	qop := ""
+	for _, q := range strings.Split(challenge["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	ncValue := fmt.Sprintf("%08x", nc)
+	var response string
+	if qop == "" {
+		response = sum(fmt.Sprintf("%s:%s:%s", ha1, nonce, ha2))
+	} else {
+		response = sum(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, nonce, ncValue, cnonce, qop, ha2))
+	}
+
+	params := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, realm),
+		fmt.Sprintf(`nonce="%s"`, nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if algorithm != "" {
+		params = append(params, fmt.Sprintf("algorithm=%s", algorithm))
+	}
+	if qop != "" {
+		params = append(params, fmt.Sprintf("qop=%s", qop), fmt.Sprintf("nc=%s", ncValue), fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		params = append(params, fmt.Sprintf(`opaque="%s"`, opaque))
+	}
+	return fmt.Sprintf("Digest %s", strings.Join(params, ", ")), true
+}
+
+// CookieAuthenticator imports cookies exported from a logged-in browser, as
+// a Netscape cookies.txt or a JSON array of cookies.
+type CookieAuthenticator struct {
+	Filename string
+}
+
+func (a *CookieAuthenticator) Authenticate(c *colly.Collector) error {
+	f, err := os.Open(a.Filename)
+	if err != nil {
+		return fmt.Errorf("failed to open cookie file:%s:%v", a.Filename, err)
+	}
+	defer f.Close()
+	cookies, err := ReadCookies(f)
+	if err != nil {
+		return fmt.Errorf("failed to read cookie file:%s:%v", a.Filename, err)
+	}
+	return SetCookies(c, cookies)
+}
+
+func SetCookies(c *colly.Collector, cookies []*http.Cookie) error {
+	byURL := make(map[string][]*http.Cookie)
+	for _, cookie := range cookies {
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		path := cookie.Path
+		if path == "" {
+			path = "/"
+		}
+		u := fmt.Sprintf("%s://%s%s", scheme, strings.TrimPrefix(cookie.Domain, "."), path)
+		byURL[u] = append(byURL[u], cookie)
+	}
+	for u, cs := range byURL {
+		if err := c.SetCookies(u, cs); err != nil {
+			return fmt.Errorf("failed to set cookies:%s:%v", u, err)
+		}
+	}
+	return nil
+}
+
+type jsonCookie struct {
+	Name           string  `json:"name"`
+	Value          string  `json:"value"`
+	Domain         string  `json:"domain"`
+	Path           string  `json:"path"`
+	Secure         bool    `json:"secure"`
+	HttpOnly       bool    `json:"httpOnly"`
+	Expires        float64 `json:"expires"`
+	ExpirationDate float64 `json:"expirationDate"`
+}
+
+func ReadCookies(r io.Reader) ([]*http.Cookie, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		return readJSONCookies(trimmed)
+	}
+	return readNetscapeCookies(b)
+}
+
+func readJSONCookies(b []byte) (cookies []*http.Cookie, err error) {
+	jcs := []jsonCookie{}
+	if err := json.Unmarshal(b, &jcs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cookies:%v", err)
+	}
+	for _, jc := range jcs {
+		cookie := &http.Cookie{
+			Name:     jc.Name,
+			Value:    jc.Value,
+			Domain:   jc.Domain,
+			Path:     jc.Path,
+			Secure:   jc.Secure,
+			HttpOnly: jc.HttpOnly,
+		}
+		expires := jc.Expires
+		if expires == 0 {
+			expires = jc.ExpirationDate
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(int64(expires), 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+func readNetscapeCookies(b []byte) (cookies []*http.Cookie, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("invalid cookie line:%d:%s", n, line)
+		}
+		cookie := &http.Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.ToUpper(fields[3]) == "TRUE",
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie expires:%d:%s", n, fields[4])
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
@@ -0,0 +1,191 @@
+package goscraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestDigestAuthorization(t *testing.T) {
+	// RFC 2617 3.5 example
+	challenge, ok := parseDigestChallenge(`Digest realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`)
+	if !ok {
+		t.Fatalf("failed to parse challenge")
+	}
+	header, ok := digestAuthorization(challenge, "Mufasa", "Circle Of Life", http.MethodGet, "/dir/index.html", 1, "0a4f113b")
+	if !ok {
+		t.Fatalf("failed to make authorization")
+	}
+	if !strings.Contains(header, `response="6629fae49393a05397450978507c4ef1"`) {
+		t.Errorf("not matched response: %s", header)
+	}
+	if !strings.Contains(header, `opaque="5ccc069c403ebaf9f0171e9517f40e41"`) || !strings.Contains(header, "nc=00000001") {
+		t.Errorf("not matched header: %s", header)
+	}
+}
+
+func TestFormAuthenticator(t *testing.T) {
+	loggedIn := false
+	var agents, appCookies, otherCookies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/app/auth/login":
+			agents = append(agents, r.UserAgent())
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/app"})
+			fmt.Fprint(w, `<form action="../session" method="post">
+<input type="hidden" name="csrf" value="t1">
+<input name="username"><input type="password" name="password">
+</form>`)
+		case r.Method == http.MethodPost && r.URL.Path == "/app/session":
+			agents = append(agents, r.UserAgent())
+			cookie, err := r.Cookie("session")
+			loggedIn = err == nil && cookie.Value == "s1" &&
+				r.FormValue("csrf") == "t1" && r.FormValue("username") == "user" && r.FormValue("password") == "pass"
+		case r.URL.Path == "/app/a":
+			appCookies = append(appCookies, r.Header.Get("Cookie"))
+		default:
+			otherCookies = append(otherCookies, r.Header.Get("Cookie"))
+		}
+	}))
+	defer ts.Close()
+
+	a := &FormAuthenticator{
+		PageURL: ts.URL + "/app/auth/login",
+		Data:    map[string]string{"username": "user", "password": "pass"},
+	}
+	c := colly.NewCollector(colly.UserAgent("test-agent"))
+	if err := a.Authenticate(c); err != nil {
+		t.Errorf("error in Authenticate:%v", err)
+	}
+	if !loggedIn {
+		t.Errorf("not logged in")
+	}
+	if !reflect.DeepEqual(agents, []string{"test-agent", "test-agent"}) {
+		t.Errorf("not logged in by the collector: %v", agents)
+	}
+	// the cookie keeps its Path
+	c.Visit(ts.URL + "/app/a")
+	c.Visit(ts.URL + "/b")
+	if !reflect.DeepEqual(appCookies, []string{"session=s1"}) || !reflect.DeepEqual(otherCookies, []string{""}) {
+		t.Errorf("not matched cookies: %v, other: %v", appCookies, otherCookies)
+	}
+}
+
+func TestHeaderAuthenticator(t *testing.T) {
+	var headers, others []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		others = append(others, r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+
+	c := colly.NewCollector(colly.AllowURLRevisit())
+	a := BearerAuthenticator("token")
+	a.Hosts = []string{strings.TrimPrefix(ts.URL, "http://")}
+	a.Authenticate(c)
+	a.Authenticate(c)
+	c.Visit(ts.URL)
+	c.Visit(other.URL)
+	if len(headers) != 1 || headers[0] != "Bearer token" {
+		t.Errorf("not matched headers: %v", headers)
+	}
+	if len(others) != 1 || others[0] != "" {
+		t.Errorf("leaked headers to other host: %v", others)
+	}
+
+	// the hosts of Entry and LoginURL by default
+	headers, others = nil, nil
+	lsc, err := NewLinkScraper(&Config{
+		Logger:   logger,
+		Auth:     BasicAuthenticator("user", "pass"),
+		Entry:    ts.URL + "/",
+		LoginURL: ts.URL + "/login",
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	lsc.Login()
+	lsc.Collector.Visit(ts.URL + "/a")
+	lsc.Collector.Visit(other.URL + "/a")
+	if len(headers) != 1 || !strings.HasPrefix(headers[0], "Basic ") || len(others) != 1 || others[0] != "" {
+		t.Errorf("not matched headers: %v, other: %v", headers, others)
+	}
+}
+
+func TestDigestAuthenticator(t *testing.T) {
+	status := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth", nonce="n1"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		challenge, _ := parseDigestChallenge(header)
+		expect, _ := digestAuthorization(map[string]string{"realm": "test", "qop": "auth", "nonce": "n1"},
+			"user", "pass", r.Method, r.URL.RequestURI(), 1, challenge["cnonce"])
+		if header != expect {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	var others []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		others = append(others, r.Header.Get("Authorization"))
+		w.Header().Set("WWW-Authenticate", `Digest realm="other", qop="auth", nonce="n2"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer other.Close()
+
+	c := colly.NewCollector()
+	c.OnResponse(func(r *colly.Response) {
+		status = r.StatusCode
+	})
+	a := &DigestAuthenticator{Username: "user", Password: "pass", Hosts: []string{strings.TrimPrefix(ts.URL, "http://")}}
+	a.Authenticate(c)
+	c.Visit(ts.URL)
+	if status != http.StatusOK {
+		t.Errorf("not authorized: %d", status)
+	}
+	// neither the challenge of ts nor its own is answered to other hosts
+	c.Visit(other.URL)
+	if len(others) != 1 || others[0] != "" {
+		t.Errorf("leaked digest to other host: %v", others)
+	}
+}
+
+func TestReadCookies(t *testing.T) {
+	tests := map[string]string{
+		"netscape": "# Netscape HTTP Cookie File\n" +
+			".example.com\tTRUE\t/\tFALSE\t0\tsession\ts1\n" +
+			"#HttpOnly_example.com\tFALSE\t/app\tTRUE\t2000000000\ttoken\tt1\n",
+		"json": `[{"name":"session","value":"s1","domain":".example.com","path":"/"},
+{"name":"token","value":"t1","domain":"example.com","path":"/app","secure":true,"httpOnly":true,"expirationDate":2000000000}]`,
+	}
+	for format, content := range tests {
+		cookies, err := ReadCookies(strings.NewReader(content))
+		if err != nil {
+			t.Errorf("error in ReadCookies:%s:%v", format, err)
+			continue
+		}
+		if len(cookies) != 2 {
+			t.Errorf("not matched cookies:%s: %v", format, cookies)
+			continue
+		}
+		if cookies[0].Name != "session" || cookies[0].Value != "s1" || !cookies[0].Expires.IsZero() {
+			t.Errorf("not matched cookie:%s: %v", format, cookies[0])
+		}
+		if cookies[1].Path != "/app" || !cookies[1].Secure || !cookies[1].HttpOnly || cookies[1].Expires.Unix() != 2000000000 {
+			t.Errorf("not matched cookie:%s: %v", format, cookies[1])
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package goscraper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gocolly/colly"
+)
+
+type BrokenLink struct {
+	URL        string   `json:"url"`
+	StatusCode int      `json:"status_code"`
+	Error      string   `json:"error"`
+	From       []string `json:"from"`
+}
+
+// ErrorCollector records failed URLs by Canonicalizer, the one of the links
+// to report them with.
+type ErrorCollector struct {
+	Canonicalizer *Canonicalizer
+	mu            sync.Mutex
+	errors        map[string]*BrokenLink
+}
+
+func NewErrorCollector() *ErrorCollector {
+	return &ErrorCollector{
+		errors: make(map[string]*BrokenLink),
+	}
+}
+
+// Collect records the failed response r, or clears the failure of its URL on
+// success, e.g. of a retry or a resumed crawl.
+func (c *ErrorCollector) Collect(r *colly.Response, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := c.key(r.Request.URL)
+	if err == nil {
+		if r.StatusCode < http.StatusBadRequest {
+			delete(c.errors, key)
+			return
+		}
+		err = fmt.Errorf("%s", http.StatusText(r.StatusCode))
+	}
+	c.errors[key] = &BrokenLink{
+		URL:        key,
+		StatusCode: r.StatusCode,
+		Error:      err.Error(),
+	}
+}
+
+// restore records the failed result of key from an earlier run.
+func (c *ErrorCollector) restore(key string, res *LinkInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if u, err := url.Parse(key); err == nil {
+		key = c.key(u)
+	}
+	if res.Error == "" && res.StatusCode < http.StatusBadRequest {
+		delete(c.errors, key)
+		return
+	}
+	msg := res.Error
+	if msg == "" {
+		msg = http.StatusText(res.StatusCode)
+	}
+	c.errors[key] = &BrokenLink{
+		URL:        key,
+		StatusCode: res.StatusCode,
+		Error:      msg,
+	}
+}
+
+func (c *ErrorCollector) key(u *url.URL) string {
+	return targetKey(c.Canonicalizer.Canonicalize(u))
+}
+
+func (c *ErrorCollector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.errors)
+}
+
+// Report returns the failed URLs sorted by URL, each with the pages of links
+// that point to it.
+func (c *ErrorCollector) Report(links Links) (broken []*BrokenLink) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	froms := make(map[string]map[string]bool)
+	for l := range links {
+		key := c.key(&l.To)
+		if _, ok := c.errors[key]; !ok {
+			continue
+		}
+		if froms[key] == nil {
+			froms[key] = make(map[string]bool)
+		}
+		froms[key][l.From.String()] = true
+	}
+	for key, b := range c.errors {
+		report := *b
+		report.From = []string{}
+		for from := range froms[key] {
+			report.From = append(report.From, from)
+		}
+		sort.Strings(report.From)
+		broken = append(broken, &report)
+	}
+	sort.Slice(broken, func(i, j int) bool {
+		return broken[i].URL < broken[j].URL
+	})
+	return broken
+}
+
+func (ls *LinkScraper) BrokenLinks() []*BrokenLink {
+	if ls.Errors == nil {
+		return nil
+	}
+	return ls.Errors.Report(ls.Links)
+}
+
+func (ls *LinkScraper) OutputBrokenLinks() (err error) {
+	filename := MakeOutFilename(fmt.Sprintf("%s_broken", ls.OutFile), ls.BrokenType)
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open output file:%s:%v", filename, err)
+	}
+	defer f.Close()
+	err = WriteBrokenLinks(ls.BrokenLinks(), f, ls.BrokenType)
+	if err != nil {
+		return fmt.Errorf("failed to write broken links:%s:%v", f.Name(), err)
+	}
+	level.Info(ls.Logger).Log("msg", "write broken links", "filename", filename)
+	return nil
+}
+
+func WriteBrokenLinks(broken []*BrokenLink, w io.Writer, outtype string) (err error) {
+	switch outtype {
+	case OptOUTPUTCSV:
+		return writeBrokenLinks2Csv(broken, w)
+	case OptOUTPUTJSON:
+		if broken == nil {
+			broken = []*BrokenLink{}
+		}
+		b, err := json.Marshal(broken)
+		if err != nil {
+			return fmt.Errorf("failed to marshal:%v", err)
+		}
+		_, err = w.Write(b)
+		return err
+	case OptOUTPUTMD:
+		return writeBrokenLinks2Markdown(broken, w)
+	default:
+		return fmt.Errorf("not supported type:%s", outtype)
+	}
+}
+
+func writeBrokenLinks2Csv(broken []*BrokenLink, w io.Writer) (err error) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"url",
+		"status",
+		"error",
+		"from",
+	})
+	for _, b := range broken {
+		froms := b.From
+		if len(froms) == 0 {
+			froms = []string{""}
+		}
+		for _, from := range froms {
+			if err := cw.Write([]string{
+				b.URL,
+				fmt.Sprintf("%d", b.StatusCode),
+				b.Error,
+				from,
+			}); err != nil {
+				return fmt.Errorf("failed to write csv record:%v", err)
+			}
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv:%v", err)
+	}
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ", "\r", "")
+
+func writeBrokenLinks2Markdown(broken []*BrokenLink, w io.Writer) (err error) {
+	lines := []string{
+		"| url | status | error | from |",
+		"| --- | --- | --- | --- |",
+	}
+	for _, b := range broken {
+		froms := make([]string, len(b.From))
+		for i, from := range b.From {
+			froms[i] = markdownEscaper.Replace(from)
+		}
+		lines = append(lines, fmt.Sprintf("| %s | %d | %s | %s |",
+			markdownEscaper.Replace(b.URL),
+			b.StatusCode,
+			markdownEscaper.Replace(b.Error),
+			strings.Join(froms, "<br>"),
+		))
+	}
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
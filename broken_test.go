@@ -0,0 +1,68 @@
+package goscraper
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestErrorCollector(t *testing.T) {
+	from1, _ := url.Parse("http://example.com/1")
+	from2, _ := url.Parse("http://example.com/2")
+	ok, _ := url.Parse("http://example.com/ok")
+	notFound, _ := url.Parse("http://example.com/missing")
+	timeout, _ := url.Parse("http://example.com/slow")
+	testLinks := Links{
+		Link{From: *from2, To: *notFound}: {Seq: 1},
+		Link{From: *from1, To: *notFound}: {Seq: 2},
+		Link{From: *from1, To: *ok}:       {Seq: 3},
+	}
+
+	c := NewErrorCollector()
+	c.Collect(&colly.Response{Request: &colly.Request{URL: ok}, StatusCode: http.StatusOK}, nil)
+	c.Collect(&colly.Response{Request: &colly.Request{URL: notFound}, StatusCode: http.StatusNotFound}, errors.New("Not Found"))
+	c.Collect(&colly.Response{Request: &colly.Request{URL: timeout}}, errors.New("timeout"))
+
+	expect := []*BrokenLink{
+		{URL: notFound.String(), StatusCode: http.StatusNotFound, Error: "Not Found", From: []string{from1.String(), from2.String()}},
+		{URL: timeout.String(), Error: "timeout", From: []string{}},
+	}
+	broken := c.Report(testLinks)
+	if !reflect.DeepEqual(expect, broken) {
+		t.Errorf("not matched,\nwant: %v,\nhave: %v", expect, broken)
+	}
+
+	tests := map[string]string{
+		OptOUTPUTCSV: `url,status,error,from
+http://example.com/missing,404,Not Found,http://example.com/1
+http://example.com/missing,404,Not Found,http://example.com/2
+http://example.com/slow,0,timeout,
+`,
+		OptOUTPUTMD: `| url | status | error | from |
+| --- | --- | --- | --- |
+| http://example.com/missing | 404 | Not Found | http://example.com/1<br>http://example.com/2 |
+| http://example.com/slow | 0 | timeout |  |
+`,
+	}
+	for outtype, expect := range tests {
+		var buf bytes.Buffer
+		if err := WriteBrokenLinks(broken, &buf, outtype); err != nil {
+			t.Errorf("error in WriteBrokenLinks:%v", err)
+		}
+		if buf.String() != expect {
+			t.Errorf("not matched: %s,\nwant: %v,\nhave: %v", outtype, expect, buf.String())
+		}
+	}
+
+	// cleared by a later success, e.g. of a retry or a resumed crawl
+	c.Collect(&colly.Response{Request: &colly.Request{URL: timeout}, StatusCode: http.StatusOK}, nil)
+	c.restore(notFound.String(), &LinkInfo{StatusCode: http.StatusOK})
+	if broken := c.Report(testLinks); len(broken) != 0 || c.Len() != 0 {
+		t.Errorf("not cleared: %v", broken)
+	}
+}
@@ -0,0 +1,138 @@
+package goscraper
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultStripParams are tracking and well-known session id query keys, not
+// generic ones like sid, which may be real params of pages.
+var DefaultStripParams = []string{
+	"utm_*", "gclid", "fbclid", "msclkid", "mc_cid", "mc_eid", "_ga",
+	"jsessionid", "phpsessid", "aspsessionid*",
+}
+
+var DefaultCanonicalizer = &Canonicalizer{StripParams: DefaultStripParams}
+
+// Canonicalizer rewrites the URLs of links to dedupe them, so that a page has
+// one link. Scheme and host are lowercased, default ports, fragments and
+// StripParams removed, and query params sorted by key. A nil Canonicalizer
+// keeps URLs as they are.
+type Canonicalizer struct {
+	// KeepFragment keeps fragments, for sites routing on them.
+	KeepFragment bool
+	// TrimSlash removes the trailing slash of paths but the root.
+	TrimSlash bool
+	// StripParams are query keys to remove, matched case-insensitively with *
+	// as a wildcard. Matched ;key=value path params are removed as well, e.g.
+	// ;jsessionid=.
+	StripParams []string
+}
+
+// Link returns a copy of link with canonical From and To, the key to dedupe
+// links. Crawls still visit the URLs as found.
+func (c *Canonicalizer) Link(link *Link) *Link {
+	l := *link
+	l.From = *c.Canonicalize(&link.From)
+	l.To = *c.Canonicalize(&link.To)
+	return &l
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalize returns the canonical copy of u.
+func (c *Canonicalizer) Canonicalize(u *url.URL) *url.URL {
+	cu := *u
+	if c == nil || cu.Opaque != "" {
+		return &cu
+	}
+	cu.Scheme = strings.ToLower(cu.Scheme)
+	cu.Host = strings.ToLower(cu.Host)
+	if port := cu.Port(); port != "" && port == defaultPorts[cu.Scheme] {
+		cu.Host = strings.TrimSuffix(cu.Host, ":"+port)
+	}
+	if !c.KeepFragment {
+		cu.Fragment = ""
+	}
+	if strings.Contains(cu.Path, ";") {
+		cu.Path = c.stripPathParams(cu.Path)
+		cu.RawPath = ""
+	}
+	switch {
+	case cu.Path == "" && cu.Host != "":
+		cu.Path = "/"
+	case c.TrimSlash && len(cu.Path) > 1 && strings.HasSuffix(cu.Path, "/"):
+		cu.Path = strings.TrimRight(cu.Path, "/")
+		cu.RawPath = strings.TrimRight(cu.RawPath, "/")
+		if cu.Path == "" {
+			cu.Path = "/"
+		}
+	}
+	cu.RawQuery = c.query(cu.RawQuery)
+	cu.ForceQuery = false
+	return &cu
+}
+
+// query sorts the params of raw by key, keeping the order of the values of
+// a key, without escaping them again.
+func (c *Canonicalizer) query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	type param struct{ key, raw string }
+	var params []param
+	for _, p := range strings.Split(raw, "&") {
+		if p == "" {
+			continue
+		}
+		key := p
+		if i := strings.Index(p, "="); i >= 0 {
+			key = p[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if c.strip(key) {
+			continue
+		}
+		params = append(params, param{key: key, raw: p})
+	}
+	sort.SliceStable(params, func(i, j int) bool { return params[i].key < params[j].key })
+	ps := make([]string, len(params))
+	for i, p := range params {
+		ps[i] = p.raw
+	}
+	return strings.Join(ps, "&")
+}
+
+func (c *Canonicalizer) stripPathParams(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		parts := strings.Split(seg, ";")
+		kept := parts[:1]
+		for _, part := range parts[1:] {
+			if c.strip(strings.SplitN(part, "=", 2)[0]) {
+				continue
+			}
+			kept = append(kept, part)
+		}
+		segments[i] = strings.Join(kept, ";")
+	}
+	return strings.Join(segments, "/")
+}
+
+func (c *Canonicalizer) strip(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range c.StripParams {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if ok, _ := path.Match(pattern, key); ok && pattern != "" {
+			return true
+		}
+	}
+	return false
+}
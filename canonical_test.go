@@ -0,0 +1,107 @@
+package goscraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	trim := &Canonicalizer{TrimSlash: true, StripParams: DefaultStripParams}
+	keep := &Canonicalizer{KeepFragment: true}
+	for _, tc := range []struct {
+		c      *Canonicalizer
+		in     string
+		expect string
+	}{
+		{DefaultCanonicalizer, "HTTP://Example.COM:80/a", "http://example.com/a"},
+		{DefaultCanonicalizer, "https://example.com:443", "https://example.com/"},
+		{DefaultCanonicalizer, "https://example.com:8443/a", "https://example.com:8443/a"},
+		{DefaultCanonicalizer, "http://example.com/a#top", "http://example.com/a"},
+		{DefaultCanonicalizer, "http://example.com/a?b=1&a=2&b=0", "http://example.com/a?a=2&b=1&b=0"},
+		{DefaultCanonicalizer, "http://example.com/a?utm_source=x&UTM_Medium=y&id=1&PHPSESSID=z", "http://example.com/a?id=1"},
+		{DefaultCanonicalizer, "http://example.com/a?utm_source=x", "http://example.com/a"},
+		{DefaultCanonicalizer, "http://example.com/a?sid=1&sessionid=2", "http://example.com/a?sessionid=2&sid=1"},
+		{DefaultCanonicalizer, "http://example.com/a?q=a%20b&p", "http://example.com/a?p&q=a%20b"},
+		{DefaultCanonicalizer, "http://example.com/a;jsessionid=abc?x=1", "http://example.com/a?x=1"},
+		{DefaultCanonicalizer, "http://example.com/a/", "http://example.com/a/"},
+		{trim, "http://example.com/a/", "http://example.com/a"},
+		{trim, "http://example.com/", "http://example.com/"},
+		{keep, "http://example.com/#/users?id=1", "http://example.com/#/users?id=1"},
+		{keep, "http://example.com/?utm_source=x", "http://example.com/?utm_source=x"},
+		{DefaultCanonicalizer, "javascript:void(0)", "javascript:void(0)"},
+		{nil, "HTTP://Example.COM:80/a#top", "http://Example.COM:80/a#top"},
+	} {
+		u, err := url.Parse(tc.in)
+		if err != nil {
+			t.Fatalf("error in Parse:%v", err)
+		}
+		before := u.String()
+		if have := tc.c.Canonicalize(u).String(); have != tc.expect {
+			t.Errorf("not matched canonical url of %s,\nwant: %s,\nhave: %s", tc.in, tc.expect, have)
+		}
+		if u.String() != before {
+			t.Errorf("changed url: %s", tc.in)
+		}
+	}
+}
+
+func TestCanonicalScrape(t *testing.T) {
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/a">a</a><a href="/a#top">a</a><a href="/a?utm_source=mail">a</a>`+
+				`<a href="/b?y=1&x=2">b</a><a href="/b?x=2&y=1">b</a><a href="/c?utm_source=mail">c</a>`)
+		case "/c":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	lsc, err := NewLinkScraper(&Config{
+		Logger: logger,
+		Entry:  ts.URL + "/",
+		Sink:   &recordSink{},
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if err := lsc.Scrape(); err != nil {
+		t.Errorf("error in Scrape:%v", err)
+	}
+	if len(lsc.Links) != 3 {
+		t.Fatalf("not deduped links: %v", lsc.Links)
+	}
+	for l, info := range lsc.Links {
+		switch l.To.RequestURI() {
+		case "/a":
+			if info.SeenCount != 4 {
+				t.Errorf("not counted seen: %v", info)
+			}
+		case "/b?x=2&y=1", "/c":
+		default:
+			t.Errorf("not canonical link: %s", l.To.String())
+		}
+		status := http.StatusOK
+		if l.To.Path == "/c" {
+			status = http.StatusNotFound
+		}
+		if info.StatusCode != status {
+			t.Errorf("not tracked status: %s, %v", l.To.String(), info)
+		}
+	}
+	// visited as found
+	if expect := []string{"/", "/a", "/b?y=1&x=2", "/c?utm_source=mail"}; !reflect.DeepEqual(expect, requested) {
+		t.Errorf("not matched requests,\nwant: %v,\nhave: %v", expect, requested)
+	}
+	broken := lsc.BrokenLinks()
+	if len(broken) != 1 || broken[0].URL != ts.URL+"/c" || !reflect.DeepEqual([]string{ts.URL + "/"}, broken[0].From) {
+		t.Errorf("not matched broken links: %v", broken)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/viper"
+	gos "github.com/ynishi/goscraper"
+	"github.com/ynishi/goscraper/graph"
+)
+
+// analyze prints the graph analysis of links in a json or jsonl output as
+// json, e.g. goscraper analyze output_20180601100000.json
+func analyze(args []string) int {
+	if len(args) != 1 {
+		return usageError("analyze", "one links file is needed")
+	}
+	links, err := gos.ReadLinksJSONFile(args[0])
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read links", "error", err)
+		return exitError
+	}
+	entry := viper.GetString(gos.OptENTRY)
+	if !flags.Changed(gos.OptENTRY) {
+		sorted, err := gos.SortLinks(links, gos.OrderDISCOVERY)
+		if err == nil && len(sorted) > 0 {
+			entry = sorted[0].From.String()
+		}
+	}
+	b, err := json.MarshalIndent(graph.Analyze(links, entry), "", "  ")
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to marshal report", "error", err)
+		return exitError
+	}
+	fmt.Println(string(b))
+	return exitOK
+}
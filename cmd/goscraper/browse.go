@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/viper"
+	gos "github.com/ynishi/goscraper"
+)
+
+// browseInput browses the links of an earlier crawl, e.g.
+// goscraper browse --input output_20180601100000.json
+func browseInput(args []string) int {
+	links, code := readSummary("browse", args)
+	if code != exitOK {
+		return code
+	}
+	return browse(links)
+}
+
+// readSummary reads the links of input with similar urls merged.
+func readSummary(name string, args []string) (gos.Links, int) {
+	if len(args) != 0 || viper.GetString(gos.OptINPUT) == "" {
+		return nil, usageError(name, "--input is needed")
+	}
+	links, err := gos.ReadLinksFile(viper.GetString(gos.OptINPUT))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read links", "error", err)
+		return nil, exitError
+	}
+	similarity, err := newSimilarity()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to select similarity", "error", err)
+		return nil, exitError
+	}
+	links, err = gos.SummaryLinkBy(links, similarity)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to summary ", "error", err)
+		return nil, exitError
+	}
+	return links, exitOK
+}
+
+func browse(links gos.Links) int {
+	driver, err := gos.NewDriver()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to new Web driver", "error", err)
+		return exitError
+	}
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+		viper.GetString(gos.OptDBUSERNAME),
+		viper.GetString(gos.OptDBPASSWORD),
+		viper.GetString(gos.OptDBHOST),
+		viper.GetString(gos.OptDBPORT),
+		viper.GetString(gos.OptDBDATABASE)))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to open db connection", "error", err)
+		return exitError
+	}
+	defer db.Close()
+
+	browser, err := gos.NewBrowser(
+		&gos.BrowserConfig{
+			Driver: driver,
+			Db:     db,
+			Logger: logger,
+			Links:  links,
+		},
+	)
+	err = browser.Browse()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to browse", "error", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gocolly/colly"
+	"github.com/gocolly/colly/debug"
+	"github.com/spf13/viper"
+	gos "github.com/ynishi/goscraper"
+)
+
+// crawl scrapes the links from entry into outfile, e.g.
+// goscraper crawl --entry https://example.com/ --outtype json
+func crawl(args []string) int {
+	if len(args) != 0 {
+		return usageError("crawl", "no argument is needed")
+	}
+
+	u, err := url.Parse(viper.GetString(gos.OptENTRY))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed parse entry url", "error", err)
+		return exitError
+	}
+
+	opts := []func(*colly.Collector){
+		colly.UserAgent(viper.GetString(gos.OptUA)),
+		colly.AllowedDomains(append(strings.Split(viper.GetString(gos.OptDOMAIN), ","), u.Host)...),
+		colly.AllowURLRevisit(),
+		colly.Debugger(&debug.LogDebugger{}),
+		colly.MaxDepth(viper.GetInt(gos.OptMAXDEPTH)),
+	}
+
+	if viper.GetString(gos.OptURLFILTER) != "" {
+		opts = append(opts, colly.URLFilters(gos.Str2filters(viper.GetString(gos.OptURLFILTER), ",")...))
+	}
+
+	if viper.GetString(gos.OptDISURLFILTER) != "" {
+		opts = append(opts, colly.DisallowedURLFilters(gos.Str2filters(viper.GetString(gos.OptDISURLFILTER), ",")...))
+	}
+
+	similarity, err := newSimilarity()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to select similarity", "error", err)
+		return exitError
+	}
+
+	if viper.GetBool(gos.OptCOLLAPSE) {
+		gos.RegisterGraphSinks(similarity)
+	}
+
+	var formFiller *gos.FormFiller
+	if viper.GetString(gos.OptFORMRULES) != "" {
+		rules, err := gos.LoadFormRules(viper.GetString(gos.OptFORMRULES))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load form rules", "error", err)
+			return exitError
+		}
+		formFiller, err = gos.NewFormFiller(rules)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to construct FormFiller", "error", err)
+			return exitError
+		}
+	}
+
+	var safetyRules []*gos.SafetyRule
+	if viper.GetString(gos.OptSAFETYRULES) != "" {
+		safetyRules, err = gos.LoadSafetyRules(viper.GetString(gos.OptSAFETYRULES))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load safety rules", "error", err)
+			return exitError
+		}
+	}
+	safety, err := gos.NewSafetyGuard(strings.Split(viper.GetString(gos.OptSAFETY), ","), safetyRules)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to construct SafetyGuard", "error", err)
+		return exitError
+	}
+
+	loginData := map[string]string{
+		viper.GetString(gos.OptFORM_USERNAME): viper.GetString(gos.OptUSERNAME),
+		viper.GetString(gos.OptFORM_PASSWORD): viper.GetString(gos.OptPASSWORD),
+	}
+	auth, err := newAuthenticator(loginData)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to construct Authenticator", "error", err)
+		return exitError
+	}
+
+	var state *gos.CrawlState
+	switch {
+	case viper.GetString(gos.OptSTATEFILE) != "":
+		state, err = gos.OpenCrawlState(viper.GetString(gos.OptSTATEFILE), viper.GetBool(gos.OptRESUME))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to open state", "error", err)
+			return exitError
+		}
+	case viper.GetBool(gos.OptRESUME):
+		level.Error(logger).Log("msg", "no statefile to resume")
+		return exitError
+	}
+
+	var session gos.SessionCheck
+	if viper.GetString(gos.OptSESSIONCHECK) != "" {
+		session, err = gos.NewSessionCheck(viper.GetString(gos.OptSESSIONCHECK), viper.GetString(gos.OptCHECKLOGIN))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to construct SessionCheck", "error", err)
+			return exitError
+		}
+	}
+
+	linkScraper, err := gos.NewLinkScraper(
+		&gos.Config{
+			Collector:    colly.NewCollector(opts...),
+			Links:        make(gos.Links),
+			Logger:       logger,
+			LoginURL:     viper.GetString(gos.OptLOGINURL),
+			LoginData:    loginData,
+			Auth:         auth,
+			Entry:        viper.GetString(gos.OptENTRY),
+			OutFile:      viper.GetString(gos.OptOUTFILE),
+			OutType:      viper.GetString(gos.OptOUTTYPE),
+			Order:        viper.GetString(gos.OptORDER),
+			LinkSelector: viper.GetString(gos.OptLINKSELECTOR),
+			IsDoPost:     viper.GetBool(gos.OptISDOPOST),
+			Similarity:   similarity,
+			BrokenType:   viper.GetString(gos.OptBROKENTYPE),
+			FormFiller:   formFiller,
+			CheckLogin:   viper.GetString(gos.OptCHECKLOGIN),
+			Session:      session,
+			MaxRelogin:   viper.GetInt(gos.OptMAXRELOGIN),
+			Safety:       safety,
+			State:        state,
+			Stream:       viper.GetBool(gos.OptSTREAM),
+
+			Parallelism:       viper.GetInt(gos.OptPARALLELISM),
+			DomainParallelism: viper.GetInt(gos.OptDOMAINPARALLELISM),
+			Delay:             viper.GetDuration(gos.OptDELAY),
+			RandomDelay:       viper.GetDuration(gos.OptRANDOMDELAY),
+
+			Politeness: &gos.Politeness{
+				Rate:              viper.GetFloat64(gos.OptRATELIMIT),
+				Burst:             viper.GetInt(gos.OptBURST),
+				Robots:            viper.GetBool(gos.OptROBOTS),
+				UserAgent:         viper.GetString(gos.OptUA),
+				Backoff:           viper.GetDuration(gos.OptBACKOFF),
+				MaxBackoff:        viper.GetDuration(gos.OptMAXBACKOFF),
+				MaxBackoffRetries: viper.GetInt(gos.OptBACKOFFRETRIES),
+				Budget:            viper.GetInt(gos.OptBUDGET),
+			},
+			Retry: &gos.RetryPolicy{
+				MaxRetries: viper.GetInt(gos.OptMAXRETRIES),
+				BaseDelay:  viper.GetDuration(gos.OptRETRYDELAY),
+				MaxDelay:   viper.GetDuration(gos.OptMAXRETRYDELAY),
+			},
+			Canonicalizer: &gos.Canonicalizer{
+				KeepFragment: viper.GetBool(gos.OptKEEPFRAGMENT),
+				TrimSlash:    viper.GetBool(gos.OptTRIMSLASH),
+				StripParams:  strings.Split(viper.GetString(gos.OptSTRIPPARAMS), ","),
+			},
+		},
+	)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to construct LinkScraper", "error", err)
+		return exitError
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	err = linkScraper.ScrapeContext(ctx)
+	if err := state.Close(); err != nil {
+		level.Error(logger).Log("msg", "failed to close state", "error", err)
+	}
+	if err == context.Canceled {
+		level.Warn(logger).Log("msg", "interrupted scrape, wrote links found so far", "links", len(linkScraper.Links))
+		return exitInterrupted
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to scrape", "error", err)
+		return exitError
+	}
+	level.Info(logger).Log("msg", "finished scrape", "relogin", linkScraper.ReloginCount())
+
+	if broken := linkScraper.BrokenLinks(); len(broken) > 0 {
+		level.Warn(logger).Log("msg", "found broken links", "count", len(broken))
+		if viper.GetBool(gos.OptFAILONBROKEN) {
+			return exitBrokenLinks
+		}
+	}
+
+	return exitOK
+}
+
+// signalContext is cancelled on the first SIGINT or SIGTERM, to stop the
+// crawl and write the links found so far. The second one exits at once.
+func signalContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case s := <-sig:
+			level.Warn(logger).Log("msg", "stopping scrape, send again to exit now", "signal", s)
+			cancel()
+		case <-done:
+			return
+		}
+		select {
+		case <-sig:
+			os.Exit(exitInterrupted)
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		close(done)
+		cancel()
+	}
+}
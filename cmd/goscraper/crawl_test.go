@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCrawlResume(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	interrupt := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		stop := interrupt && r.URL.Path == "/"
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			if stop {
+				// interrupted while fetching the entry
+				syscall.Kill(os.Getpid(), syscall.SIGINT)
+				time.Sleep(200 * time.Millisecond)
+			}
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a>`)
+		case "/a":
+			fmt.Fprint(w, `<a href="/">home</a><a href="/c">c</a>`)
+		case "/b", "/c":
+			fmt.Fprint(w, `<a href="/">home</a>`)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "goscraper")
+	if err != nil {
+		t.Fatalf("failed to create temp dir:%v", err)
+	}
+	defer os.RemoveAll(dir)
+	args := []string{
+		"crawl",
+		"--entry", ts.URL + "/",
+		"--domain", "127.0.0.1",
+		"--loginURL", ts.URL + "/login",
+		"--maxdepth", "3",
+		"--outfile", filepath.Join(dir, "output"),
+		"--outtype", "jsonl",
+		"--statefile", filepath.Join(dir, "state.jsonl"),
+	}
+
+	if code := run(args); code != exitInterrupted {
+		t.Fatalf("not interrupted: %d", code)
+	}
+	mu.Lock()
+	interrupt = false
+	mu.Unlock()
+	if code := run(append(args, "--resume")); code != exitOK {
+		t.Fatalf("not resumed: %d", code)
+	}
+	// the entry done by the last run is not fetched again
+	expect := map[string]int{"/login": 2, "/": 1, "/a": 1, "/b": 1, "/c": 1}
+	if !reflect.DeepEqual(expect, hits) {
+		t.Errorf("not matched hits,\nwant: %v,\nhave: %v", expect, hits)
+	}
+}
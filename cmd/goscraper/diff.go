@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/viper"
+	gos "github.com/ynishi/goscraper"
+)
+
+// diff prints the links added, removed and changed between two csv, json or jsonl
+// outputs, e.g. goscraper diff --difftype md yesterday.json today.json
+func diff(args []string) int {
+	if len(args) != 2 {
+		return usageError("diff", "old and new links files are needed")
+	}
+	old, err := gos.ReadLinksFile(args[0])
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read old links", "error", err)
+		return exitError
+	}
+	new, err := gos.ReadLinksFile(args[1])
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read new links", "error", err)
+		return exitError
+	}
+	similarity, err := newSimilarity()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to select similarity", "error", err)
+		return exitError
+	}
+	if err := gos.WriteLinkDiff(gos.DiffBy(old, new, similarity), os.Stdout, viper.GetString(gos.OptDIFFTYPE)); err != nil {
+		level.Error(logger).Log("msg", "failed to write diff", "error", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -1,20 +1,25 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
-	"net/url"
 	"os"
 	"strings"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
-	"github.com/gocolly/colly"
-	"github.com/gocolly/colly/debug"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	gos "github.com/ynishi/goscraper"
 )
 
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitBrokenLinks = 2
+	exitUsage       = 64
+	exitInterrupted = 130
+)
+
 var logger log.Logger
 
 func init() {
@@ -45,6 +50,34 @@ func init() {
 	viper.SetDefault(gos.OptLINKSELECTOR, "a[href],form,[onclick]")
 	viper.SetDefault(gos.OptISDOPOST, false)
 	viper.SetDefault(gos.OptCHECKLOGIN, "loggedin")
+	viper.SetDefault(gos.OptSIMILARITY, gos.SimilarityKEYSET)
+	viper.SetDefault(gos.OptORDER, gos.OrderSORTED)
+	viper.SetDefault(gos.OptFAILONBROKEN, false)
+	viper.SetDefault(gos.OptAUTH, gos.AuthPOST)
+	viper.SetDefault(gos.OptSESSIONCHECK, "")
+	viper.SetDefault(gos.OptMAXRELOGIN, 3)
+	viper.SetDefault(gos.OptSAFETY, strings.Join(gos.DefaultSafetyPresets, ","))
+	viper.SetDefault(gos.OptSTREAM, false)
+	viper.SetDefault(gos.OptRESUME, false)
+	viper.SetDefault(gos.OptCOLLAPSE, false)
+	viper.SetDefault(gos.OptDIFFTYPE, gos.DiffTEXT)
+	viper.SetDefault(gos.OptPARALLELISM, 1)
+	viper.SetDefault(gos.OptDOMAINPARALLELISM, 0)
+	viper.SetDefault(gos.OptDELAY, "0s")
+	viper.SetDefault(gos.OptRANDOMDELAY, "0s")
+	viper.SetDefault(gos.OptRATELIMIT, 0.0)
+	viper.SetDefault(gos.OptBURST, 1)
+	viper.SetDefault(gos.OptROBOTS, false)
+	viper.SetDefault(gos.OptBACKOFF, "5s")
+	viper.SetDefault(gos.OptMAXBACKOFF, "1m")
+	viper.SetDefault(gos.OptBACKOFFRETRIES, 3)
+	viper.SetDefault(gos.OptBUDGET, 0)
+	viper.SetDefault(gos.OptMAXRETRIES, 2)
+	viper.SetDefault(gos.OptRETRYDELAY, "1s")
+	viper.SetDefault(gos.OptMAXRETRYDELAY, "30s")
+	viper.SetDefault(gos.OptKEEPFRAGMENT, false)
+	viper.SetDefault(gos.OptTRIMSLASH, false)
+	viper.SetDefault(gos.OptSTRIPPARAMS, strings.Join(gos.DefaultStripParams, ","))
 
 	viper.SetEnvPrefix(gos.OptSCRP) // env SCRP_XXX
 	viper.BindEnv(gos.OptDOMAIN)    // comma separated list, no use colly default env
@@ -59,7 +92,7 @@ func init() {
 	viper.BindEnv(gos.OptCONFIG)
 	viper.BindEnv(gos.OptUSECONFIG)
 	viper.BindEnv(gos.OptOUTFILE)
-	viper.BindEnv(gos.OptOUTTYPE)
+	viper.BindEnv(gos.OptOUTTYPE)      // csv, json, jsonl, md, html, xml sitemap, dot, graphml, gexf or mermaid
 	viper.BindEnv(gos.OptURLFILTER)    // comma separated list
 	viper.BindEnv(gos.OptDISURLFILTER) //comma separated list
 	viper.BindEnv(gos.OptDBUSERNAME)
@@ -70,109 +103,298 @@ func init() {
 	viper.BindEnv(gos.OptLINKSELECTOR)
 	viper.BindEnv(gos.OptISDOPOST)
 	viper.BindEnv(gos.OptCHECKLOGIN)
-
-	if viper.GetBool(gos.OptUSECONFIG) {
-		viper.SetConfigName(viper.GetString(gos.OptCONFIG))
-		viper.AddConfigPath(".")
-		err := viper.ReadInConfig()
-		if err != nil {
-			level.Error(logger).Log("msg", "failed read config", "error", err)
-			os.Exit(1)
-		}
-	}
+	viper.BindEnv(gos.OptSIMILARITY)
+	viper.BindEnv(gos.OptSIGNIFKEYS) // comma separated list
+	viper.BindEnv(gos.OptORDER)
+	viper.BindEnv(gos.OptBROKENTYPE) // csv, json or md, no report if empty
+	viper.BindEnv(gos.OptFAILONBROKEN)
+	viper.BindEnv(gos.OptFORMRULES) // toml file, see formrules.toml
+	viper.BindEnv(gos.OptAUTH)      // post, form, basic, digest, bearer, header or cookie
+	viper.BindEnv(gos.OptLOGINPAGE)
+	viper.BindEnv(gos.OptAUTHTOKEN)
+	viper.BindEnv(gos.OptAUTHHEADERS) // comma separated list of name:value
+	viper.BindEnv(gos.OptAUTHHOSTS)   // comma separated list, entry and loginurl hosts if empty
+	viper.BindEnv(gos.OptCOOKIEFILE)  // netscape cookies.txt or json
+	viper.BindEnv(gos.OptMAXRELOGIN)
+	viper.BindEnv(gos.OptSESSIONCHECK) // contains, regexp, selector, redirect, status or cookie of checklogin, no check if empty
+	viper.BindEnv(gos.OptSAFETY)       // comma separated list of logout, destructive or none
+	viper.BindEnv(gos.OptSAFETYRULES)  // toml file, see safetyrules.toml
+	viper.BindEnv(gos.OptSTATEFILE)    // jsonl file to save progress, needed for --resume
+	viper.BindEnv(gos.OptSTREAM)       // write links as found and updated at the end, outfile "-" for stdout
+	viper.BindEnv(gos.OptCOLLAPSE)     // collapse similar urls in graph outputs
+	viper.BindEnv(gos.OptINPUT)
+	viper.BindEnv(gos.OptDIFFTYPE)
+	viper.BindEnv(gos.OptPARALLELISM)
+	viper.BindEnv(gos.OptDOMAINPARALLELISM)
+	viper.BindEnv(gos.OptDELAY) // duration, e.g. 500ms
+	viper.BindEnv(gos.OptRANDOMDELAY)
+	viper.BindEnv(gos.OptRATELIMIT) // requests per second per host, no limit if 0
+	viper.BindEnv(gos.OptBURST)
+	viper.BindEnv(gos.OptROBOTS)
+	viper.BindEnv(gos.OptBACKOFF) // duration after 429 or 503 without Retry-After
+	viper.BindEnv(gos.OptMAXBACKOFF)
+	viper.BindEnv(gos.OptBACKOFFRETRIES)
+	viper.BindEnv(gos.OptBUDGET) // max requests, no limit if 0
+	viper.BindEnv(gos.OptMAXRETRIES)
+	viper.BindEnv(gos.OptRETRYDELAY) // doubled on each retry up to maxretrydelay
+	viper.BindEnv(gos.OptMAXRETRYDELAY)
+	viper.BindEnv(gos.OptKEEPFRAGMENT)
+	viper.BindEnv(gos.OptTRIMSLASH)
+	viper.BindEnv(gos.OptSTRIPPARAMS) // comma separated list, * as a wildcard
 }
 
-func main() {
+type command struct {
+	name    string
+	args    string
+	short   string
+	options []string
+	run     func(args []string) int
+}
 
-	u, err := url.Parse(viper.GetString(gos.OptENTRY))
-	if err != nil {
-		level.Error(logger).Log("msg", "failed parse entry url", "error", err)
-		os.Exit(1)
+var (
+	crawlOptions = []string{
+		gos.OptENTRY, gos.OptDOMAIN, gos.OptUA, gos.OptMAXDEPTH, gos.OptURLFILTER, gos.OptDISURLFILTER,
+		gos.OptLINKSELECTOR, gos.OptISDOPOST, gos.OptOUTFILE, gos.OptOUTTYPE, gos.OptORDER, gos.OptSTREAM,
+		gos.OptCOLLAPSE, gos.OptSIMILARITY, gos.OptSIGNIFKEYS, gos.OptBROKENTYPE, gos.OptFAILONBROKEN,
+		gos.OptAUTH, gos.OptLOGINURL, gos.OptLOGINPAGE, gos.OptFORM_USERNAME, gos.OptUSERNAME,
+		gos.OptFORM_PASSWORD, gos.OptPASSWORD, gos.OptAUTHTOKEN, gos.OptAUTHHEADERS, gos.OptAUTHHOSTS, gos.OptCOOKIEFILE,
+		gos.OptCHECKLOGIN, gos.OptSESSIONCHECK, gos.OptMAXRELOGIN, gos.OptFORMRULES, gos.OptSAFETY,
+		gos.OptSAFETYRULES, gos.OptSTATEFILE, gos.OptRESUME, gos.OptPARALLELISM, gos.OptDOMAINPARALLELISM,
+		gos.OptDELAY, gos.OptRANDOMDELAY, gos.OptRATELIMIT, gos.OptBURST, gos.OptROBOTS, gos.OptBACKOFF,
+		gos.OptMAXBACKOFF, gos.OptBACKOFFRETRIES, gos.OptBUDGET, gos.OptMAXRETRIES, gos.OptRETRYDELAY,
+		gos.OptMAXRETRYDELAY, gos.OptKEEPFRAGMENT, gos.OptTRIMSLASH, gos.OptSTRIPPARAMS,
+	}
+	browseOptions = []string{
+		gos.OptINPUT, gos.OptSIMILARITY, gos.OptSIGNIFKEYS,
+		gos.OptDBUSERNAME, gos.OptDBPASSWORD, gos.OptDBHOST, gos.OptDBPORT, gos.OptDBDATABASE,
 	}
+	commands = []*command{
+		{"crawl", "", "crawl links from entry and write them to outfile", crawlOptions, crawl},
+		{"summarize", "", "print links of input with similar urls merged as json", []string{gos.OptINPUT, gos.OptORDER, gos.OptSIMILARITY, gos.OptSIGNIFKEYS}, summarize},
+		{"browse", "", "click links of input in chrome, logging the queries of each click", browseOptions, browseInput},
+		{"analyze", "links.json", "print the link graph analysis of a json or jsonl output", []string{gos.OptENTRY}, analyze},
+		{"diff", "old.json new.json", "print links added, removed and changed between two outputs", []string{gos.OptDIFFTYPE, gos.OptSIMILARITY, gos.OptSIGNIFKEYS}, diff},
+		{"report", "", "write links of input as outtype to outfile", []string{gos.OptINPUT, gos.OptOUTFILE, gos.OptOUTTYPE, gos.OptORDER, gos.OptCOLLAPSE, gos.OptSIMILARITY, gos.OptSIGNIFKEYS}, report},
+	}
+	flags *pflag.FlagSet
+)
+
+var optionUsages = map[string]string{
+	gos.OptCONFIG:        "config file name without extension, read if useConfig",
+	gos.OptUSECONFIG:     "read options from config",
+	gos.OptENTRY:         "entry url, for analyze the first page of the input by default",
+	gos.OptDOMAIN:        "comma separated list of allowed domains",
+	gos.OptUA:            "user agent",
+	gos.OptMAXDEPTH:      "max depth of links from entry",
+	gos.OptURLFILTER:     "comma separated list of regexps of urls to visit",
+	gos.OptDISURLFILTER:  "comma separated list of regexps of urls not to visit",
+	gos.OptLINKSELECTOR:  "css selector of links",
+	gos.OptISDOPOST:      "submit forms",
+	gos.OptOUTFILE:       "output file name without extension, - for stdout",
+	gos.OptOUTTYPE:       "csv, json, jsonl, md, html, xml sitemap, dot, graphml, gexf or mermaid",
+	gos.OptORDER:         "sorted or discovery",
+	gos.OptSTREAM:        "write links as found unsorted, and update rows of the changed ones at the end",
+	gos.OptCOLLAPSE:      "collapse similar urls in graph outputs",
+	gos.OptSIMILARITY:    "exact, keyset, significant, template or fragment",
+	gos.OptSIGNIFKEYS:    "comma separated list of query keys for significant similarity",
+	gos.OptBROKENTYPE:    "csv, json or md, no report if empty",
+	gos.OptFAILONBROKEN:  "exit with 2 if broken links are found",
+	gos.OptAUTH:          "post, form, basic, digest, bearer, header or cookie",
+	gos.OptLOGINURL:      "url to post login data",
+	gos.OptLOGINPAGE:     "url of the login form for form auth",
+	gos.OptFORM_USERNAME: "form field of username",
+	gos.OptUSERNAME:      "username",
+	gos.OptFORM_PASSWORD: "form field of password",
+	gos.OptPASSWORD:      "password",
+	gos.OptAUTHTOKEN:     "token of bearer auth",
+	gos.OptAUTHHEADERS:   "comma separated list of name:value for header auth",
+	gos.OptAUTHHOSTS:     "comma separated list of hosts to send basic, digest, bearer or header auth, entry and loginurl hosts if empty",
+	gos.OptCOOKIEFILE:    "netscape cookies.txt or json for cookie auth",
+	gos.OptCHECKLOGIN:    "value of sessioncheck, e.g. text only in logged in pages",
+	gos.OptSESSIONCHECK:  "contains, regexp, selector, redirect, status or cookie, no relogin check if empty",
+	gos.OptMAXRELOGIN:    "max relogin when session is lost",
+	gos.OptFORMRULES:     "toml file of form rules, see formrules.toml",
+	gos.OptSAFETY:        "comma separated list of logout, destructive or none",
+	gos.OptSAFETYRULES:   "toml file of safety rules, see safetyrules.toml",
+	gos.OptSTATEFILE:     "jsonl file to save progress, needed for --resume",
+	gos.OptRESUME:        "continue the crawl saved in statefile",
+	gos.OptINPUT:         "csv, json or jsonl output of an earlier crawl",
+	gos.OptDIFFTYPE:      "text, json or md",
+	gos.OptDBUSERNAME:    "mysql username",
+	gos.OptDBPASSWORD:    "mysql password",
+	gos.OptDBHOST:        "mysql host",
+	gos.OptDBPORT:        "mysql port",
+	gos.OptDBDATABASE:    "mysql database",
+
+	gos.OptPARALLELISM:       "max requests in flight, crawl concurrently if more than 1",
+	gos.OptDOMAINPARALLELISM: "max requests in flight per allowed domain",
+	gos.OptDELAY:             "delay between requests to a domain, e.g. 500ms",
+	gos.OptRANDOMDELAY:       "max extra random delay between requests to a domain",
 
-	opts := []func(*colly.Collector){
-		colly.UserAgent(viper.GetString(gos.OptUA)),
-		colly.AllowedDomains(append(strings.Split(viper.GetString(gos.OptDOMAIN), ","), u.Host)...),
-		colly.AllowURLRevisit(),
-		colly.Debugger(&debug.LogDebugger{}),
-		colly.MaxDepth(viper.GetInt(gos.OptMAXDEPTH)),
+	gos.OptRATELIMIT:      "max requests per second to a host, no limit if 0",
+	gos.OptBURST:          "requests to a host allowed at once by ratelimit",
+	gos.OptROBOTS:         "skip links disallowed by robots.txt and wait its Crawl-delay",
+	gos.OptBACKOFF:        "wait after 429 or 503 without Retry-After",
+	gos.OptMAXBACKOFF:     "max wait after 429 or 503",
+	gos.OptBACKOFFRETRIES: "max retries of a request after 429 or 503",
+	gos.OptBUDGET:         "max requests of the crawl, no limit if 0",
+
+	gos.OptMAXRETRIES:    "max retries of a GET failed by a transient error, e.g. timeout or 5xx",
+	gos.OptRETRYDELAY:    "delay before the first retry, doubled on each retry with jitter",
+	gos.OptMAXRETRYDELAY: "max delay before a retry",
+
+	gos.OptKEEPFRAGMENT: "keep url fragments of links, e.g. with fragment similarity",
+	gos.OptTRIMSLASH:    "remove trailing slashes of link urls",
+	gos.OptSTRIPPARAMS:  "comma separated list of query keys removed from link urls to dedupe, * as a wildcard",
+}
+
+// newFlagSet makes a flag per option, typed by the default of viper.
+func newFlagSet(c *command) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(c.name, pflag.ContinueOnError)
+	for _, name := range append(c.options, gos.OptCONFIG, gos.OptUSECONFIG) {
+		switch v := viper.Get(name).(type) {
+		case bool:
+			fs.Bool(name, v, optionUsages[name])
+		case int:
+			fs.Int(name, v, optionUsages[name])
+		default:
+			fs.String(name, viper.GetString(name), optionUsages[name])
+		}
+	}
+	fs.Usage = func() {
+		usage := strings.TrimSpace(fmt.Sprintf("goscraper %s [flags] %s", c.name, c.args))
+		fmt.Fprintf(os.Stderr, "usage: %s\n\n%s\n\nflags:\n", usage, c.short)
+		fs.PrintDefaults()
 	}
+	return fs
+}
 
-	if viper.GetString(gos.OptURLFILTER) != "" {
-		opts = append(opts, colly.URLFilters(gos.Str2filters(viper.GetString(gos.OptURLFILTER), ",")...))
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "usage: goscraper <command> [flags]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.short)
 	}
+	fmt.Fprintf(os.Stderr, "\nrun goscraper <command> --help for the flags of command\n")
+}
 
-	if viper.GetString(gos.OptDISURLFILTER) != "" {
-		opts = append(opts, colly.DisallowedURLFilters(gos.Str2filters(viper.GetString(gos.OptDISURLFILTER), ",")...))
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
 	}
+	return nil
+}
+
+func usageError(name, msg string) int {
+	fmt.Fprintf(os.Stderr, "goscraper %s: %s\n", name, msg)
+	flags.Usage()
+	return exitUsage
+}
 
-	linkScraper, err := gos.NewLinkScraper(
-		&gos.Config{
-			Collector: colly.NewCollector(opts...),
-			Links:     make(gos.Links),
-			Logger:    logger,
-			LoginURL:  viper.GetString(gos.OptLOGINURL),
-			LoginData: map[string]string{
-				viper.GetString(gos.OptFORM_USERNAME): viper.GetString(gos.OptUSERNAME),
-				viper.GetString(gos.OptFORM_PASSWORD): viper.GetString(gos.OptPASSWORD),
-			},
-			Entry:        viper.GetString(gos.OptENTRY),
-			OutFile:      viper.GetString(gos.OptOUTFILE),
-			OutType:      viper.GetString(gos.OptOUTTYPE),
-			LinkSelector: viper.GetString(gos.OptLINKSELECTOR),
-			IsDoPost:     viper.GetBool(gos.OptISDOPOST),
-		},
-	)
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to construct LinkScraper", "error", err)
-		os.Exit(1)
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		printCommands()
+		return exitUsage
 	}
+	switch args[0] {
+	case "help", "-h", "--help":
+		if len(args) > 1 && findCommand(args[1]) != nil {
+			newFlagSet(findCommand(args[1])).Usage()
+		} else {
+			printCommands()
+		}
+		return exitOK
+	}
+	c := findCommand(args[0])
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "goscraper: unknown command %q\n\n", args[0])
+		printCommands()
+		return exitUsage
+	}
+	flags = newFlagSet(c)
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == pflag.ErrHelp {
+			return exitOK
+		}
+		return usageError(c.name, err.Error())
+	}
+	viper.BindPFlags(flags)
 
-	err = linkScraper.Scrape()
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to scrape", "error", err)
-		os.Exit(1)
+	if viper.GetBool(gos.OptUSECONFIG) {
+		viper.SetConfigName(viper.GetString(gos.OptCONFIG))
+		viper.AddConfigPath(".")
+		err := viper.ReadInConfig()
+		if err != nil {
+			level.Error(logger).Log("msg", "failed read config", "error", err)
+			return exitError
+		}
 	}
+	return c.run(flags.Args())
+}
 
-	links, err := gos.SummaryLink(linkScraper.Links)
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to summary ", "error", err)
-		os.Exit(1)
+func newAuthenticator(loginData map[string]string) (gos.Authenticator, error) {
+	switch viper.GetString(gos.OptAUTH) {
+	case gos.AuthPOST:
+		return &gos.PostAuthenticator{
+			LoginURL: viper.GetString(gos.OptLOGINURL),
+			Data:     loginData,
+		}, nil
+	case gos.AuthFORM:
+		return &gos.FormAuthenticator{
+			PageURL:  viper.GetString(gos.OptLOGINPAGE),
+			LoginURL: viper.GetString(gos.OptLOGINURL),
+			Data:     loginData,
+		}, nil
+	case gos.AuthBASIC:
+		return withAuthHosts(gos.BasicAuthenticator(viper.GetString(gos.OptUSERNAME), viper.GetString(gos.OptPASSWORD))), nil
+	case gos.AuthDIGEST:
+		return &gos.DigestAuthenticator{
+			Username: viper.GetString(gos.OptUSERNAME),
+			Password: viper.GetString(gos.OptPASSWORD),
+			Hosts:    authHosts(),
+		}, nil
+	case gos.AuthBEARER:
+		return withAuthHosts(gos.BearerAuthenticator(viper.GetString(gos.OptAUTHTOKEN))), nil
+	case gos.AuthHEADER:
+		headers := make(map[string]string)
+		for _, h := range strings.Split(viper.GetString(gos.OptAUTHHEADERS), ",") {
+			kv := strings.SplitN(h, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid header:%s", h)
+			}
+			headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+		return withAuthHosts(&gos.HeaderAuthenticator{Headers: headers}), nil
+	case gos.AuthCOOKIE:
+		return &gos.CookieAuthenticator{Filename: viper.GetString(gos.OptCOOKIEFILE)}, nil
+	default:
+		return nil, fmt.Errorf("not supported auth:%s", viper.GetString(gos.OptAUTH))
 	}
-	b, err := gos.Links2Json(links)
-	fmt.Println(string(b))
+}
 
-	linkScraper.FlushURLs()
-	fmt.Println(linkScraper.URLs)
+func withAuthHosts(a *gos.HeaderAuthenticator) *gos.HeaderAuthenticator {
+	a.Hosts = authHosts()
+	return a
+}
 
-	driver, err := gos.NewDriver()
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to new Web driver", "error", err)
-		os.Exit(1)
-	}
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
-		viper.GetString(gos.OptDBUSERNAME),
-		viper.GetString(gos.OptDBPASSWORD),
-		viper.GetString(gos.OptDBHOST),
-		viper.GetString(gos.OptDBPORT),
-		viper.GetString(gos.OptDBDATABASE)))
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to open db connection", "error", err)
-		os.Exit(1)
+func authHosts() (hosts []string) {
+	for _, h := range strings.Split(viper.GetString(gos.OptAUTHHOSTS), ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, h)
+		}
 	}
-	defer db.Close()
-
-	browser, err := gos.NewBrowser(
-		&gos.BrowserConfig{
-			Driver: driver,
-			Db:     db,
-			Logger: logger,
-			Links:  links,
-		},
-	)
-	err = browser.Browse()
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to browse", "error", err)
-		os.Exit(1)
+	return hosts
+}
+
+func newSimilarity() (gos.URLSimilarity, error) {
+	var significantKeys []string
+	if viper.GetString(gos.OptSIGNIFKEYS) != "" {
+		significantKeys = strings.Split(viper.GetString(gos.OptSIGNIFKEYS), ",")
 	}
+	return gos.NewURLSimilarity(viper.GetString(gos.OptSIMILARITY), significantKeys)
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/viper"
+	gos "github.com/ynishi/goscraper"
+)
+
+// report writes the links of an earlier crawl in another output type, e.g.
+// goscraper report --input output_20180601100000.json --outtype html
+func report(args []string) int {
+	if len(args) != 0 || viper.GetString(gos.OptINPUT) == "" {
+		return usageError("report", "--input is needed")
+	}
+	links, err := gos.ReadLinksFile(viper.GetString(gos.OptINPUT))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read links", "error", err)
+		return exitError
+	}
+	if viper.GetBool(gos.OptCOLLAPSE) {
+		similarity, err := newSimilarity()
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to select similarity", "error", err)
+			return exitError
+		}
+		gos.RegisterGraphSinks(similarity)
+	}
+	sorted, err := gos.SortLinks(links, viper.GetString(gos.OptORDER))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to sort links", "error", err)
+		return exitError
+	}
+	sink, filename, err := gos.NewFileSink(viper.GetString(gos.OptOUTFILE), viper.GetString(gos.OptOUTTYPE))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to open output", "error", err)
+		return exitError
+	}
+	for _, l := range sorted {
+		if err := sink.WriteLink(l, links[l]); err != nil {
+			level.Error(logger).Log("msg", "failed to write link", "error", err)
+			return exitError
+		}
+	}
+	if err := sink.Close(); err != nil {
+		level.Error(logger).Log("msg", "failed to close output", "error", err)
+		return exitError
+	}
+	level.Info(logger).Log("msg", "write report", "filename", filename, "links", len(sorted))
+	return exitOK
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/viper"
+	gos "github.com/ynishi/goscraper"
+)
+
+// summarize prints the links of an earlier crawl with similar urls merged, e.g.
+// goscraper summarize --input output_20180601100000.csv
+func summarize(args []string) int {
+	links, code := readSummary("summarize", args)
+	if code != exitOK {
+		return code
+	}
+	b, err := gos.Links2JsonOrder(links, viper.GetString(gos.OptORDER))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to marshal links", "error", err)
+		return exitError
+	}
+	fmt.Println(string(b))
+	return exitOK
+}
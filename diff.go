@@ -0,0 +1,211 @@
+package goscraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+const DiffTEXT = "text"
+
+// LinkChange is a link found in both runs whose method or target changed.
+type LinkChange struct {
+	Old    Link     `json:"old"`
+	New    Link     `json:"new"`
+	Fields []string `json:"fields"`
+}
+
+type LinkDiff struct {
+	AddedPages   []string      `json:"added_pages"`
+	RemovedPages []string      `json:"removed_pages"`
+	Added        []Link        `json:"added"`
+	Removed      []Link        `json:"removed"`
+	Changed      []*LinkChange `json:"changed"`
+	Unchanged    int           `json:"unchanged"`
+}
+
+func (d *LinkDiff) Empty() bool {
+	return len(d.AddedPages) == 0 && len(d.RemovedPages) == 0 &&
+		len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func Diff(old, new Links) *LinkDiff {
+	return DiffBy(old, new, DefaultURLSimilarity)
+}
+
+// DiffBy compares the summaries of two runs by sim. Links with similar from
+// and to, the same method and onclick are unchanged. Remaining links from the
+// same element, by similar from, tag, id and text, are changed, and the rest
+// are added or removed.
+func DiffBy(old, new Links, sim URLSimilarity) *LinkDiff {
+	d := &LinkDiff{
+		AddedPages:   diffPages(UniqURLBy(new, sim), UniqURLBy(old, sim), sim),
+		RemovedPages: diffPages(UniqURLBy(old, sim), UniqURLBy(new, sim), sim),
+		Added:        []Link{},
+		Removed:      []Link{},
+		Changed:      []*LinkChange{},
+	}
+	oldSummary, _ := SummaryLinkBy(old, sim)
+	newSummary, _ := SummaryLinkBy(new, sim)
+	olds := sortedLinks(oldSummary)
+	news := sortedLinks(newSummary)
+	matched := make([]bool, len(olds))
+	match := func(l Link, same func(o, n Link) bool) bool {
+		for i, o := range olds {
+			if !matched[i] && same(o, l) {
+				matched[i] = true
+				return true
+			}
+		}
+		return false
+	}
+
+	rest := []Link{}
+	for _, l := range news {
+		if match(l, func(o, n Link) bool {
+			return sim.Similar(&o.From, &n.From) && sim.Similar(&o.To, &n.To) &&
+				o.Method == n.Method && o.AttrOnClick == n.AttrOnClick
+		}) {
+			d.Unchanged++
+			continue
+		}
+		rest = append(rest, l)
+	}
+	for _, l := range rest {
+		var change *LinkChange
+		match(l, func(o, n Link) bool {
+			if !sameElement(o, n, sim) {
+				return false
+			}
+			change = &LinkChange{Old: o, New: n, Fields: []string{}}
+			return true
+		})
+		if change == nil {
+			d.Added = append(d.Added, l)
+			continue
+		}
+		if change.Old.Method != change.New.Method {
+			change.Fields = append(change.Fields, "method")
+		}
+		if !sim.Similar(&change.Old.To, &change.New.To) {
+			change.Fields = append(change.Fields, "to")
+		}
+		if change.Old.AttrOnClick != change.New.AttrOnClick {
+			change.Fields = append(change.Fields, "attr_onclick")
+		}
+		d.Changed = append(d.Changed, change)
+	}
+	for i, o := range olds {
+		if !matched[i] {
+			d.Removed = append(d.Removed, o)
+		}
+	}
+	return d
+}
+
+func sameElement(l1, l2 Link, sim URLSimilarity) bool {
+	return sim.Similar(&l1.From, &l2.From) && l1.Tag == l2.Tag && l1.AttrId == l2.AttrId &&
+		strings.Join(strings.Fields(l1.Text), " ") == strings.Join(strings.Fields(l2.Text), " ")
+}
+
+// diffPages returns the urls without a similar url in others.
+func diffPages(urls, others []*url.URL, sim URLSimilarity) (pages []string) {
+	pages = []string{}
+	for _, u := range urls {
+		found := false
+		for _, o := range others {
+			if sim.Similar(u, o) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pages = append(pages, u.String())
+		}
+	}
+	return pages
+}
+
+func WriteLinkDiff(d *LinkDiff, w io.Writer, outtype string) (err error) {
+	switch outtype {
+	case "", DiffTEXT:
+		return writeLinkDiff2Text(d, w)
+	case OptOUTPUTJSON:
+		b, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal:%v", err)
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	case OptOUTPUTMD:
+		return writeLinkDiff2Markdown(d, w)
+	default:
+		return fmt.Errorf("not supported type:%s", outtype)
+	}
+}
+
+func diffLinkText(l Link) string {
+	s := strings.TrimSpace(fmt.Sprintf("%s %s %s -> %s", l.Method, l.Tag, l.From.String(), l.To.String()))
+	if text := strings.Join(strings.Fields(l.Text), " "); text != "" {
+		s = fmt.Sprintf("%s %q", s, text)
+	}
+	return s
+}
+
+func writeLinkDiff2Text(d *LinkDiff, w io.Writer) (err error) {
+	lines := []string{}
+	for _, p := range d.AddedPages {
+		lines = append(lines, "+ page "+p)
+	}
+	for _, p := range d.RemovedPages {
+		lines = append(lines, "- page "+p)
+	}
+	for _, l := range d.Added {
+		lines = append(lines, "+ "+diffLinkText(l))
+	}
+	for _, l := range d.Removed {
+		lines = append(lines, "- "+diffLinkText(l))
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s\n  => %s", diffLinkText(c.Old), diffLinkText(c.New)))
+	}
+	lines = append(lines, fmt.Sprintf("%d pages added, %d pages removed, %d links added, %d removed, %d changed, %d unchanged",
+		len(d.AddedPages), len(d.RemovedPages), len(d.Added), len(d.Removed), len(d.Changed), d.Unchanged))
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func writeLinkDiff2Markdown(d *LinkDiff, w io.Writer) (err error) {
+	lines := []string{
+		"| change | method | from | to | text |",
+		"| --- | --- | --- | --- | --- |",
+	}
+	row := func(change string, l Link) string {
+		return fmt.Sprintf("| %s | %s | %s | %s | %s |",
+			change,
+			l.Method,
+			markdownEscaper.Replace(l.From.String()),
+			markdownEscaper.Replace(l.To.String()),
+			markdownEscaper.Replace(strings.TrimSpace(l.Text)),
+		)
+	}
+	for _, p := range d.AddedPages {
+		lines = append(lines, fmt.Sprintf("| added page |  |  | %s |  |", markdownEscaper.Replace(p)))
+	}
+	for _, p := range d.RemovedPages {
+		lines = append(lines, fmt.Sprintf("| removed page |  |  | %s |  |", markdownEscaper.Replace(p)))
+	}
+	for _, l := range d.Added {
+		lines = append(lines, row("added", l))
+	}
+	for _, l := range d.Removed {
+		lines = append(lines, row("removed", l))
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, row("changed from", c.Old), row("changed to", c.New))
+	}
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
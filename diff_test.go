@@ -0,0 +1,97 @@
+package goscraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func diffLink(from, to, method, tag, text string) Link {
+	f, _ := url.Parse(from)
+	t, _ := url.Parse(to)
+	return Link{From: *f, To: *t, Method: method, Tag: tag, Text: text}
+}
+
+func TestDiff(t *testing.T) {
+	old := Links{
+		diffLink("http://example.com/", "http://example.com/items?id=1", "GET", "a", "item"): {Seq: 1},
+		diffLink("http://example.com/", "http://example.com/about", "GET", "a", "about"):     {Seq: 2},
+		diffLink("http://example.com/", "http://example.com/save", "GET", "form", ""):        {Seq: 3},
+		diffLink("http://example.com/", "http://example.com/old", "GET", "a", "old"):         {Seq: 4},
+	}
+	new := Links{
+		diffLink("http://example.com/", "http://example.com/items?id=2", "GET", "a", "item"): {Seq: 1},
+		diffLink("http://example.com/", "http://example.com/company", "GET", "a", "about"):   {Seq: 2},
+		diffLink("http://example.com/", "http://example.com/save", "POST", "form", ""):       {Seq: 3},
+		diffLink("http://example.com/", "http://example.com/contact", "GET", "a", "contact"): {Seq: 4},
+	}
+	d := Diff(old, new)
+	if d.Unchanged != 1 {
+		t.Errorf("not matched unchanged: %d", d.Unchanged)
+	}
+	if !reflect.DeepEqual(d.AddedPages, []string{"http://example.com/company", "http://example.com/contact"}) {
+		t.Errorf("not matched added pages: %v", d.AddedPages)
+	}
+	if !reflect.DeepEqual(d.RemovedPages, []string{"http://example.com/about", "http://example.com/old"}) {
+		t.Errorf("not matched removed pages: %v", d.RemovedPages)
+	}
+	if len(d.Added) != 1 || d.Added[0].Text != "contact" {
+		t.Errorf("not matched added: %v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Text != "old" {
+		t.Errorf("not matched removed: %v", d.Removed)
+	}
+	changed := map[string][]string{}
+	for _, c := range d.Changed {
+		changed[c.New.To.Path] = c.Fields
+	}
+	expect := map[string][]string{"/company": {"to"}, "/save": {"method"}}
+	if !reflect.DeepEqual(changed, expect) {
+		t.Errorf("not matched changed,\nwant: %v,\nhave: %v", expect, changed)
+	}
+
+	if !Diff(old, old).Empty() {
+		t.Errorf("not empty diff of same links")
+	}
+	if d := DiffBy(old, new, ExactSimilarity{}); d.Unchanged != 0 || len(d.Changed) != 3 {
+		t.Errorf("not matched exact diff: %d unchanged, %d changed", d.Unchanged, len(d.Changed))
+	}
+}
+
+func TestWriteLinkDiff(t *testing.T) {
+	d := Diff(
+		Links{diffLink("http://example.com/", "http://example.com/a", "GET", "a", "a|b"): {Seq: 1}},
+		Links{diffLink("http://example.com/", "http://example.com/a", "POST", "a", "a|b"): {Seq: 1}},
+	)
+	var buf bytes.Buffer
+	if err := WriteLinkDiff(d, &buf, DiffTEXT); err != nil {
+		t.Errorf("error in WriteLinkDiff:%v", err)
+	}
+	expect := `~ GET a http://example.com/ -> http://example.com/a "a|b"
+  => POST a http://example.com/ -> http://example.com/a "a|b"
+0 pages added, 0 pages removed, 0 links added, 0 removed, 1 changed, 0 unchanged
+`
+	if buf.String() != expect {
+		t.Errorf("not matched text,\nwant: %s,\nhave: %s", expect, buf.String())
+	}
+
+	buf.Reset()
+	WriteLinkDiff(d, &buf, OptOUTPUTMD)
+	if !strings.Contains(buf.String(), `| changed to | POST | http://example.com/ | http://example.com/a | a\|b |`) {
+		t.Errorf("not matched markdown: %s", buf.String())
+	}
+
+	buf.Reset()
+	WriteLinkDiff(d, &buf, OptOUTPUTJSON)
+	res := &LinkDiff{}
+	if err := json.Unmarshal(buf.Bytes(), res); err != nil || len(res.Changed) != 1 {
+		t.Errorf("not matched json: %v, %s", err, buf.String())
+	}
+
+	if err := WriteLinkDiff(d, &bytes.Buffer{}, "unknown"); err == nil {
+		t.Errorf("no error in unknown type")
+	}
+}
@@ -0,0 +1,326 @@
+package goscraper
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+const (
+	GraphDOT     = "dot"
+	GraphGRAPHML = "graphml"
+	GraphGEXF    = "gexf"
+	GraphMERMAID = "mermaid"
+)
+
+type GraphNode struct {
+	ID    string
+	Label string
+	URLs  int
+}
+
+// GraphEdge is a set of links between two nodes with the same label.
+type GraphEdge struct {
+	From  string
+	To    string
+	Label string
+	Count int
+}
+
+// LinkGraph is Links as a directed graph of pages.
+type LinkGraph struct {
+	Nodes []*GraphNode
+	Edges []*GraphEdge
+}
+
+type graphGroup struct {
+	node    *GraphNode
+	pattern *URLPattern
+	first   *url.URL
+}
+
+func edgeLabel(link Link) string {
+	text := strings.Join(strings.Fields(link.Text), " ")
+	if r := []rune(text); len(r) > 30 {
+		text = string(r[:30]) + "..."
+	}
+	label := strings.TrimSpace(fmt.Sprintf("%s %s", link.Method, link.Tag))
+	if text != "" {
+		label = fmt.Sprintf("%s: %s", label, text)
+	}
+	return label
+}
+
+// NewLinkGraph makes a node per URL, or per group of similar URLs labelled
+// with their URLPattern if sim is not nil.
+func NewLinkGraph(links Links, sim URLSimilarity) *LinkGraph {
+	g := &LinkGraph{}
+	byURL := make(map[string]*graphGroup)
+	byKey := make(map[string]*graphGroup)
+	var groups []*graphGroup
+	node := func(u url.URL) *GraphNode {
+		u.Fragment = ""
+		s := u.String()
+		if group, ok := byURL[s]; ok {
+			return group.node
+		}
+		var group *graphGroup
+		switch ks := sim.(type) {
+		case nil:
+		case KeyedURLSimilarity:
+			group = byKey[ks.Key(&u)]
+		default:
+			for _, gr := range groups {
+				if sim.Similar(gr.first, &u) {
+					group = gr
+					break
+				}
+			}
+		}
+		if group == nil {
+			group = &graphGroup{
+				node:    &GraphNode{ID: fmt.Sprintf("n%d", len(groups)+1), Label: s},
+				pattern: NewURLPattern(&u),
+				first:   &u,
+			}
+			groups = append(groups, group)
+			if ks, ok := sim.(KeyedURLSimilarity); ok {
+				byKey[ks.Key(&u)] = group
+			}
+		} else if group.pattern != nil && group.pattern.Merge(&u) != nil {
+			group.pattern = nil
+		}
+		group.node.URLs++
+		byURL[s] = group
+		return group.node
+	}
+
+	sorted, _ := SortLinks(links, OrderSORTED)
+	edges := make(map[string]*GraphEdge)
+	for _, l := range sorted {
+		from := node(l.From)
+		to := node(l.To)
+		label := edgeLabel(l)
+		key := fmt.Sprintf("%s %s %s", from.ID, to.ID, label)
+		if e, ok := edges[key]; ok {
+			e.Count++
+			continue
+		}
+		e := &GraphEdge{From: from.ID, To: to.ID, Label: label, Count: 1}
+		edges[key] = e
+		g.Edges = append(g.Edges, e)
+	}
+	for _, group := range groups {
+		if group.node.URLs > 1 && group.pattern != nil {
+			group.node.Label = group.pattern.String()
+		}
+		g.Nodes = append(g.Nodes, group.node)
+	}
+	return g
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+
+func WriteDOT(g *LinkGraph, w io.Writer) error {
+	lines := []string{"digraph links {", "  rankdir=LR;", "  node [shape=box];"}
+	for _, n := range g.Nodes {
+		lines = append(lines, fmt.Sprintf(`  "%s" [label="%s"];`, n.ID, dotEscaper.Replace(n.Label)))
+	}
+	for _, e := range g.Edges {
+		label := dotEscaper.Replace(e.Label)
+		if e.Count > 1 {
+			label = fmt.Sprintf("%s (%d)", label, e.Count)
+		}
+		lines = append(lines, fmt.Sprintf(`  "%s" -> "%s" [label="%s"];`, e.From, e.To, label))
+	}
+	lines = append(lines, "}")
+	if _, err := io.WriteString(w, strings.Join(lines, "\n")+"\n"); err != nil {
+		return fmt.Errorf("failed to write dot:%v", err)
+	}
+	return nil
+}
+
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\n", " ", "|", "#124;")
+
+func WriteMermaid(g *LinkGraph, w io.Writer) error {
+	lines := []string{"flowchart LR"}
+	for _, n := range g.Nodes {
+		lines = append(lines, fmt.Sprintf(`  %s["%s"]`, n.ID, mermaidEscaper.Replace(n.Label)))
+	}
+	for _, e := range g.Edges {
+		label := mermaidEscaper.Replace(e.Label)
+		if e.Count > 1 {
+			label = fmt.Sprintf("%s (%d)", label, e.Count)
+		}
+		lines = append(lines, fmt.Sprintf(`  %s -->|"%s"| %s`, e.From, label, e.To))
+	}
+	if _, err := io.WriteString(w, strings.Join(lines, "\n")+"\n"); err != nil {
+		return fmt.Errorf("failed to write mermaid:%v", err)
+	}
+	return nil
+}
+
+type xmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLNode struct {
+	ID   string    `xml:"id,attr"`
+	Data []xmlData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string    `xml:"id,attr"`
+	Source string    `xml:"source,attr"`
+	Target string    `xml:"target,attr"`
+	Data   []xmlData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+func writeXML(w io.Writer, v interface{}, format string) error {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s:%v", format, err)
+	}
+	if _, err := io.WriteString(w, xml.Header+string(b)+"\n"); err != nil {
+		return fmt.Errorf("failed to write %s:%v", format, err)
+	}
+	return nil
+}
+
+func WriteGraphML(g *LinkGraph, w io.Writer) error {
+	doc := &graphML{
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "urls", For: "node", AttrName: "urls", AttrType: "int"},
+			{ID: "elabel", For: "edge", AttrName: "label", AttrType: "string"},
+			{ID: "count", For: "edge", AttrName: "count", AttrType: "int"},
+		},
+		Graph: graphMLGraph{ID: "links", EdgeDefault: "directed"},
+	}
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: []xmlData{
+			{Key: "label", Value: n.Label},
+			{Key: "urls", Value: fmt.Sprintf("%d", n.URLs)},
+		}})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{ID: fmt.Sprintf("e%d", i+1), Source: e.From, Target: e.To, Data: []xmlData{
+			{Key: "elabel", Value: e.Label},
+			{Key: "count", Value: fmt.Sprintf("%d", e.Count)},
+		}})
+	}
+	return writeXML(w, doc, GraphGRAPHML)
+}
+
+type gexfNode struct {
+	ID    string `xml:"id,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type gexfEdge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Label  string `xml:"label,attr"`
+	Weight int    `xml:"weight,attr"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string     `xml:"defaultedgetype,attr"`
+	Nodes           []gexfNode `xml:"nodes>node"`
+	Edges           []gexfEdge `xml:"edges>edge"`
+}
+
+type gexf struct {
+	XMLName xml.Name  `xml:"http://gexf.net/1.2 gexf"`
+	Version string    `xml:"version,attr"`
+	Creator string    `xml:"meta>creator"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+func WriteGEXF(g *LinkGraph, w io.Writer) error {
+	doc := &gexf{Version: "1.2", Creator: "goscraper", Graph: gexfGraph{DefaultEdgeType: "directed"}}
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{ID: n.ID, Label: n.Label})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{ID: fmt.Sprintf("e%d", i+1), Source: e.From, Target: e.To, Label: e.Label, Weight: e.Count})
+	}
+	return writeXML(w, doc, GraphGEXF)
+}
+
+func WriteLinkGraph(g *LinkGraph, w io.Writer, format string) error {
+	switch format {
+	case GraphDOT:
+		return WriteDOT(g, w)
+	case GraphGRAPHML:
+		return WriteGraphML(g, w)
+	case GraphGEXF:
+		return WriteGEXF(g, w)
+	case GraphMERMAID:
+		return WriteMermaid(g, w)
+	default:
+		return fmt.Errorf("not supported graph format:%s", format)
+	}
+}
+
+// GraphSink writes the links as a graph in Format on Close. Similar URLs are
+// collapsed into a node if Similarity is set.
+type GraphSink struct {
+	Format     string
+	Similarity URLSimilarity
+	w          io.Writer
+	links      Links
+}
+
+func NewGraphSink(w io.Writer, format string, sim URLSimilarity) *GraphSink {
+	return &GraphSink{Format: format, Similarity: sim, w: w, links: make(Links)}
+}
+
+func (s *GraphSink) WriteLink(link Link, info *LinkInfo) error {
+	s.links[link] = info
+	return nil
+}
+
+func (s *GraphSink) Close() error {
+	if err := WriteLinkGraph(NewLinkGraph(s.links, s.Similarity), s.w, s.Format); err != nil {
+		return err
+	}
+	return closeWriter(s.w)
+}
+
+// RegisterGraphSinks registers the graph formats as output types, collapsing
+// similar URLs by sim if it is not nil.
+func RegisterGraphSinks(sim URLSimilarity) {
+	for _, format := range []string{GraphDOT, GraphGRAPHML, GraphGEXF, GraphMERMAID} {
+		format := format
+		RegisterLinkSink(format, func(w io.Writer) LinkSink { return NewGraphSink(w, format, sim) })
+	}
+}
+
+func init() {
+	RegisterGraphSinks(nil)
+}
@@ -0,0 +1,78 @@
+package goscraper
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func graphLinks() Links {
+	newLink := func(from, to, tag, text string) Link {
+		f, _ := url.Parse(from)
+		t, _ := url.Parse(to)
+		return Link{From: *f, To: *t, Method: "GET", Tag: tag, Text: text}
+	}
+	return Links{
+		newLink("http://example.com/", "http://example.com/items?id=1", "a", "item \"1\""): {Seq: 1},
+		newLink("http://example.com/", "http://example.com/items?id=2", "a", "item 2"):     {Seq: 2},
+		newLink("http://example.com/", "http://example.com/about", "a", "about"):           {Seq: 3},
+		newLink("http://example.com/items?id=1", "http://example.com/", "a", "home"):       {Seq: 4},
+		newLink("http://example.com/items?id=2", "http://example.com/", "a", "home"):       {Seq: 5},
+	}
+}
+
+func TestNewLinkGraph(t *testing.T) {
+	g := NewLinkGraph(graphLinks(), nil)
+	if len(g.Nodes) != 4 || len(g.Edges) != 5 {
+		t.Errorf("not matched graph: %d nodes, %d edges", len(g.Nodes), len(g.Edges))
+	}
+
+	for _, sim := range []URLSimilarity{DefaultURLSimilarity, URLSimilarityFunc(isSimilerURL)} {
+		g = NewLinkGraph(graphLinks(), sim)
+		labels := []string{}
+		for _, n := range g.Nodes {
+			labels = append(labels, n.Label)
+		}
+		expect := "http://example.com/ http://example.com/about http://example.com/items?id={id}"
+		if strings.Join(labels, " ") != expect {
+			t.Errorf("not matched nodes,\nwant: %v,\nhave: %v", expect, labels)
+		}
+		if len(g.Edges) != 4 {
+			t.Errorf("not matched edges: %v", g.Edges)
+		}
+		for _, e := range g.Edges {
+			if e.Label == "GET a: home" && e.Count != 2 {
+				t.Errorf("not merged edge: %v", e)
+			}
+		}
+	}
+}
+
+func TestWriteLinkGraph(t *testing.T) {
+	g := NewLinkGraph(graphLinks(), DefaultURLSimilarity)
+	tests := map[string]string{
+		GraphDOT:     `"n1" -> "n3" [label="GET a: item \"1\""];`,
+		GraphMERMAID: `n1 -->|"GET a: item #quot;1#quot;"| n3`,
+		GraphGRAPHML: `<data key="elabel">GET a: item &#34;1&#34;</data>`,
+		GraphGEXF:    `<edge id="e4" source="n3" target="n1" label="GET a: home" weight="2"></edge>`,
+	}
+	for format, expect := range tests {
+		var buf bytes.Buffer
+		if err := WriteLinkGraph(g, &buf, format); err != nil {
+			t.Errorf("error in WriteLinkGraph:%s:%v", format, err)
+		}
+		if !strings.Contains(buf.String(), expect) {
+			t.Errorf("not contains: %s,\nwant: %s,\nhave: %s", format, expect, buf.String())
+		}
+		if format == GraphGRAPHML || format == GraphGEXF {
+			if err := xml.Unmarshal(buf.Bytes(), new(interface{})); err != nil {
+				t.Errorf("invalid xml: %s:%v", format, err)
+			}
+		}
+	}
+	if err := WriteLinkGraph(g, &bytes.Buffer{}, "unknown"); err == nil {
+		t.Errorf("no error in unknown format")
+	}
+}
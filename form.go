@@ -0,0 +1,369 @@
+package goscraper
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+const (
+	EnctypeURLENCODED = "application/x-www-form-urlencoded"
+	EnctypeMULTIPART  = "multipart/form-data"
+)
+
+type Form struct {
+	Name    string        `json:"name,omitempty"`
+	ID      string        `json:"id,omitempty"`
+	Action  string        `json:"action"`
+	Method  string        `json:"method"`
+	Enctype string        `json:"enctype"`
+	Fields  []*FormField  `json:"fields"`
+	Submits []*FormSubmit `json:"submits"`
+}
+
+type FormField struct {
+	Name     string        `json:"name"`
+	Tag      string        `json:"tag"`
+	Type     string        `json:"type"`
+	Value    string        `json:"value"`
+	Checked  bool          `json:"checked,omitempty"`
+	Multiple bool          `json:"multiple,omitempty"`
+	Required bool          `json:"required,omitempty"`
+	Disabled bool          `json:"disabled,omitempty"`
+	Options  []*FormOption `json:"options,omitempty"`
+}
+
+type FormOption struct {
+	Value    string `json:"value"`
+	Text     string `json:"text"`
+	Selected bool   `json:"selected,omitempty"`
+}
+
+type FormSubmit struct {
+	Name        string `json:"name,omitempty"`
+	Value       string `json:"value,omitempty"`
+	Tag         string `json:"tag"`
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	FormAction  string `json:"formaction,omitempty"`
+	FormMethod  string `json:"formmethod,omitempty"`
+	FormEnctype string `json:"formenctype,omitempty"`
+}
+
+var FormTypeSubmit = map[string]bool{
+	"submit": true,
+	"image":  true,
+}
+
+func hasAttr(e *colly.HTMLElement, name string) bool {
+	_, ok := e.DOM.Attr(name)
+	return ok
+}
+
+func formMethod(method string) string {
+	if method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(method)
+}
+
+func formEnctype(enctype string) string {
+	if strings.ToLower(enctype) == EnctypeMULTIPART {
+		return EnctypeMULTIPART
+	}
+	return EnctypeURLENCODED
+}
+
+func E2Form(e *colly.HTMLElement) (form *Form, err error) {
+	if e.Name != "form" {
+		return nil, fmt.Errorf("not form element:%s", e.Name)
+	}
+	action, err := url.Parse(e.Request.AbsoluteURL(e.Attr("action")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid form action:%s:%v", e.Attr("action"), err)
+	}
+	form = &Form{
+		Name:    e.Attr("name"),
+		ID:      e.Attr("id"),
+		Action:  action.String(),
+		Method:  formMethod(e.Attr("method")),
+		Enctype: formEnctype(e.Attr("enctype")),
+		Fields:  []*FormField{},
+		Submits: []*FormSubmit{},
+	}
+	e.ForEach("input,select,textarea,button", func(_ int, ce *colly.HTMLElement) {
+		typ := strings.ToLower(ce.Attr("type"))
+		switch {
+		case ce.Name == "button" && typ == "":
+			typ = "submit"
+		case ce.Name == "input" && typ == "":
+			typ = "text"
+		case ce.Name != "input" && ce.Name != "button":
+			typ = ce.Name
+		}
+		if ce.Name == "button" || FormTypeBtn[typ] {
+			if !FormTypeSubmit[typ] || hasAttr(ce, "disabled") {
+				return
+			}
+			formAction := ""
+			if ce.Attr("formaction") != "" {
+				formAction = e.Request.AbsoluteURL(ce.Attr("formaction"))
+			}
+			form.Submits = append(form.Submits, &FormSubmit{
+				Name:        ce.Attr("name"),
+				Value:       ce.Attr("value"),
+				Tag:         ce.Name,
+				Type:        typ,
+				Text:        strings.TrimSpace(ce.Text),
+				FormAction:  formAction,
+				FormMethod:  ce.Attr("formmethod"),
+				FormEnctype: ce.Attr("formenctype"),
+			})
+			return
+		}
+		field := &FormField{
+			Name:     ce.Attr("name"),
+			Tag:      ce.Name,
+			Type:     typ,
+			Value:    ce.Attr("value"),
+			Checked:  hasAttr(ce, "checked"),
+			Multiple: hasAttr(ce, "multiple"),
+			Required: hasAttr(ce, "required"),
+			Disabled: hasAttr(ce, "disabled"),
+		}
+		switch ce.Name {
+		case "textarea":
+			field.Value = ce.Text
+		case "select":
+			ce.ForEach("option", func(_ int, oe *colly.HTMLElement) {
+				value := oe.Attr("value")
+				if !hasAttr(oe, "value") {
+					value = strings.TrimSpace(oe.Text)
+				}
+				field.Options = append(field.Options, &FormOption{
+					Value:    value,
+					Text:     strings.TrimSpace(oe.Text),
+					Selected: hasAttr(oe, "selected"),
+				})
+			})
+		}
+		form.Fields = append(form.Fields, field)
+	})
+	return form, nil
+}
+
+// DefaultValues returns what a browser would send without user input: checked
+// boxes, selected options and value attributes of enabled fields.
+func (f *Form) DefaultValues() url.Values {
+	values := url.Values{}
+	for _, field := range f.Fields {
+		if field.Name == "" || field.Disabled {
+			continue
+		}
+		switch field.Type {
+		case "checkbox", "radio":
+			if !field.Checked {
+				continue
+			}
+			if field.Value == "" {
+				values.Add(field.Name, "on")
+			} else {
+				values.Add(field.Name, field.Value)
+			}
+		case "select":
+			selected := false
+			for _, o := range field.Options {
+				if o.Selected {
+					values.Add(field.Name, o.Value)
+					selected = true
+				}
+			}
+			if !selected && !field.Multiple && len(field.Options) > 0 {
+				values.Add(field.Name, field.Options[0].Value)
+			}
+		default:
+			values.Add(field.Name, field.Value)
+		}
+	}
+	return values
+}
+
+func (f *Form) fileNames() (names []string) {
+	for _, field := range f.Fields {
+		if field.Type == "file" && field.Name != "" && !field.Disabled {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+type FormSubmission struct {
+	Method  string     `json:"method"`
+	URL     string     `json:"url"`
+	Enctype string     `json:"enctype"`
+	Values  url.Values `json:"values"`
+	Files   []string   `json:"files,omitempty"`
+}
+
+// Submission builds the request made by clicking submit, which may be nil
+// for forms without a submit button.
+func (f *Form) Submission(submit *FormSubmit, values url.Values) *FormSubmission {
+	s := &FormSubmission{
+		Method:  f.Method,
+		URL:     f.Action,
+		Enctype: f.Enctype,
+		Values:  url.Values{},
+		Files:   f.fileNames(),
+	}
+	for k, vs := range values {
+		s.Values[k] = append([]string{}, vs...)
+	}
+	if submit == nil {
+		return s
+	}
+	if submit.FormAction != "" {
+		s.URL = submit.FormAction
+	}
+	if submit.FormMethod != "" {
+		s.Method = formMethod(submit.FormMethod)
+	}
+	if submit.FormEnctype != "" {
+		s.Enctype = formEnctype(submit.FormEnctype)
+	}
+	switch {
+	case submit.Name == "":
+	case submit.Type == "image":
+		s.Values.Set(submit.Name+".x", "0")
+		s.Values.Set(submit.Name+".y", "0")
+	default:
+		s.Values.Set(submit.Name, submit.Value)
+	}
+	return s
+}
+
+// Submissions returns one submission per submit button, or a single one
+// without a button if the form has none.
+func (f *Form) Submissions(values url.Values) (subs []*FormSubmission) {
+	if len(f.Submits) == 0 {
+		return []*FormSubmission{f.Submission(nil, values)}
+	}
+	for _, submit := range f.Submits {
+		subs = append(subs, f.Submission(submit, values))
+	}
+	return subs
+}
+
+func (s *FormSubmission) isFile(name string) bool {
+	for _, f := range s.Files {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FormSubmission) Body() (contentType string, body []byte, err error) {
+	if s.Enctype != EnctypeMULTIPART {
+		return EnctypeURLENCODED, []byte(s.Values.Encode()), nil
+	}
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, k := range sortedKeys(s.Values) {
+		for _, v := range s.Values[k] {
+			if s.isFile(k) {
+				if _, err := mw.CreateFormFile(k, v); err != nil {
+					return "", nil, fmt.Errorf("failed to write multipart file:%s:%v", k, err)
+				}
+				continue
+			}
+			if err := mw.WriteField(k, v); err != nil {
+				return "", nil, fmt.Errorf("failed to write multipart field:%s:%v", k, err)
+			}
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to close multipart:%v", err)
+	}
+	return mw.FormDataContentType(), buf.Bytes(), nil
+}
+
+func (s *FormSubmission) NewRequest() (*http.Request, error) {
+	if s.Method == http.MethodGet {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid form url:%s:%v", s.URL, err)
+		}
+		u.RawQuery = s.Values.Encode()
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+	contentType, body, err := s.Body()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(s.Method, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to new request:%v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+// Requester sends requests, which is *colly.Request for requests from a page
+// or *colly.Collector for top level ones.
+type Requester interface {
+	Visit(URL string) error
+	PostRaw(URL string, requestData []byte) error
+}
+
+// Submit sends the submission from the page of r, so it counts as a child of
+// that page for colly's depth limit.
+func (s *FormSubmission) Submit(r Requester) error {
+	switch {
+	case s.Method == http.MethodGet:
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return fmt.Errorf("invalid form url:%s:%v", s.URL, err)
+		}
+		u.RawQuery = s.Values.Encode()
+		return r.Visit(u.String())
+	case s.Enctype == EnctypeMULTIPART:
+		// by Body, as PostMultipart keeps only a value of a field and no files
+		contentType, body, err := s.Body()
+		if err != nil {
+			return err
+		}
+		hdr := http.Header{}
+		hdr.Set("Content-Type", contentType)
+		switch r := r.(type) {
+		case *colly.Request:
+			req, err := r.New(s.Method, r.AbsoluteURL(s.URL), bytes.NewReader(body))
+			if err != nil {
+				return fmt.Errorf("failed to new request:%v", err)
+			}
+			req.Depth = r.Depth + 1
+			*req.Headers = hdr
+			return req.Do()
+		case *colly.Collector:
+			return r.Request(s.Method, s.URL, bytes.NewReader(body), nil, hdr)
+		default:
+			return fmt.Errorf("not supported requester of multipart:%T", r)
+		}
+	default:
+		return r.PostRaw(s.URL, []byte(s.Values.Encode()))
+	}
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,198 @@
+package goscraper
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+const testFormHTML = `<html><body>
+<form name="edit" action="/save" method="post" enctype="multipart/form-data">
+  <input type="hidden" name="csrf" value="token">
+  <input name="title" value="hello" required>
+  <input type="checkbox" name="publish" checked>
+  <input type="checkbox" name="draft" value="1">
+  <input type="radio" name="color" value="red">
+  <input type="radio" name="color" value="blue" checked>
+  <input type="text" name="disabled" value="x" disabled>
+  <select name="category">
+    <option value="a">A</option>
+    <option value="b" selected>B</option>
+  </select>
+  <select name="tags" multiple>
+    <option>t1</option>
+    <option>t2</option>
+  </select>
+  <textarea name="body">text</textarea>
+  <input type="reset" value="Reset">
+  <button name="op" value="save">Save</button>
+  <button name="op" value="preview" formaction="/preview" formmethod="get">Preview</button>
+</form>
+</body></html>`
+
+func TestE2Form(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testFormHTML)
+	}))
+	defer ts.Close()
+
+	var form *Form
+	c := colly.NewCollector()
+	c.OnHTML("form", func(e *colly.HTMLElement) {
+		var err error
+		form, err = E2Form(e)
+		if err != nil {
+			t.Errorf("error in E2Form:%v", err)
+		}
+	})
+	c.Visit(ts.URL)
+	if form == nil {
+		t.Fatalf("not found form")
+	}
+
+	if form.Action != ts.URL+"/save" || form.Method != http.MethodPost || form.Enctype != EnctypeMULTIPART {
+		t.Errorf("not matched form: %v", form)
+	}
+	if len(form.Fields) != 10 || len(form.Submits) != 2 {
+		t.Errorf("not matched fields or submits: %d, %d", len(form.Fields), len(form.Submits))
+	}
+	if !form.Fields[1].Required {
+		t.Errorf("not required field: %v", form.Fields[1])
+	}
+
+	expect := url.Values{
+		"csrf":     {"token"},
+		"title":    {"hello"},
+		"publish":  {"on"},
+		"color":    {"blue"},
+		"category": {"b"},
+		"body":     {"text"},
+	}
+	if values := form.DefaultValues(); !reflect.DeepEqual(expect, values) {
+		t.Errorf("not matched,\nwant: %v,\nhave: %v", expect, values)
+	}
+
+	subs := form.Submissions(form.DefaultValues())
+	if len(subs) != 2 {
+		t.Fatalf("not matched submissions: %v", subs)
+	}
+	if subs[0].URL != ts.URL+"/save" || subs[0].Values.Get("op") != "save" {
+		t.Errorf("not matched submission: %v", subs[0])
+	}
+	if subs[1].URL != ts.URL+"/preview" || subs[1].Method != http.MethodGet || subs[1].Values.Get("op") != "preview" {
+		t.Errorf("not matched submission: %v", subs[1])
+	}
+}
+
+func TestFormSubmission(t *testing.T) {
+	s := &FormSubmission{
+		Method:  http.MethodPost,
+		URL:     "http://example.com/save",
+		Enctype: EnctypeMULTIPART,
+		Values:  url.Values{"title": {"hello"}, "file": {""}},
+		Files:   []string{"file"},
+	}
+	contentType, body, err := s.Body()
+	if err != nil {
+		t.Fatalf("error in Body:%v", err)
+	}
+	if !strings.HasPrefix(contentType, EnctypeMULTIPART) || !strings.Contains(string(body), `name="file"; filename=""`) {
+		t.Errorf("not matched multipart body: %s", body)
+	}
+	req, err := s.NewRequest()
+	if err != nil {
+		t.Fatalf("error in NewRequest:%v", err)
+	}
+	if err := req.ParseMultipartForm(1024); err != nil {
+		t.Fatalf("error in ParseMultipartForm:%v", err)
+	}
+	if req.FormValue("title") != "hello" {
+		t.Errorf("not matched multipart form: %v", req.MultipartForm)
+	}
+
+	s.Enctype = EnctypeURLENCODED
+	req, err = s.NewRequest()
+	if err != nil {
+		t.Fatalf("error in NewRequest:%v", err)
+	}
+	b, _ := ioutil.ReadAll(req.Body)
+	if req.Header.Get("Content-Type") != EnctypeURLENCODED || string(b) != "file=&title=hello" {
+		t.Errorf("not matched urlencoded form: %s", b)
+	}
+
+	s.Method = http.MethodGet
+	req, err = s.NewRequest()
+	if err != nil {
+		t.Fatalf("error in NewRequest:%v", err)
+	}
+	if !strings.HasSuffix(req.URL.String(), "/save?file=&title=hello") {
+		t.Errorf("not matched get form: %v", req.URL)
+	}
+}
+
+func TestFormSubmit(t *testing.T) {
+	var posted []url.Values
+	var files int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/save" {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<form action="/save" method="post" enctype="multipart/form-data">`+
+				`<input type="file" name="file"><select name="tags" multiple><option>t1</option><option>t2</option></select></form>`)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(b), `name="file"; filename=""`) {
+			files++
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		if err := r.ParseMultipartForm(1024); err != nil {
+			t.Errorf("error in ParseMultipartForm:%v", err)
+			return
+		}
+		posted = append(posted, url.Values(r.MultipartForm.Value))
+	}))
+	defer ts.Close()
+
+	var depths []int
+	var sub *FormSubmission
+	c := colly.NewCollector()
+	c.OnRequest(func(r *colly.Request) {
+		depths = append(depths, r.Depth)
+	})
+	c.OnHTML("form", func(e *colly.HTMLElement) {
+		form, err := E2Form(e)
+		if err != nil {
+			t.Fatalf("error in E2Form:%v", err)
+		}
+		values := form.DefaultValues()
+		values["tags"] = []string{"t1", "t2"}
+		sub = form.Submissions(values)[0]
+		if err := sub.Submit(e.Request); err != nil {
+			t.Errorf("error in Submit:%v", err)
+		}
+	})
+	c.Visit(ts.URL + "/")
+	if sub == nil {
+		t.Fatalf("not found form")
+	}
+	c = colly.NewCollector(colly.AllowURLRevisit())
+	if err := sub.Submit(c); err != nil {
+		t.Errorf("error in Submit:%v", err)
+	}
+
+	expect := []url.Values{{"file": {""}, "tags": {"t1", "t2"}}, {"file": {""}, "tags": {"t1", "t2"}}}
+	if !reflect.DeepEqual(expect, posted) || files != 2 {
+		t.Errorf("not matched posted,\nwant: %v,\nhave: %v, files: %v", expect, posted, files)
+	}
+	if !reflect.DeepEqual([]int{1, 2}, depths) {
+		t.Errorf("not matched depths: %v", depths)
+	}
+}
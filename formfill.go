@@ -0,0 +1,141 @@
+package goscraper
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	GeneratorEMAIL  = "email"
+	GeneratorINT    = "int"
+	GeneratorDATE   = "date"
+	GeneratorCHOICE = "choice"
+)
+
+// FormRule sets the value of fields matching all of Name, Type and Match.
+// The value is Value as is, or made by Generator when it is set.
+type FormRule struct {
+	Name      string   `mapstructure:"name"`
+	Type      string   `mapstructure:"type"`
+	Match     string   `mapstructure:"match"`
+	Value     string   `mapstructure:"value"`
+	Generator string   `mapstructure:"generator"`
+	Min       int      `mapstructure:"min"`
+	Max       int      `mapstructure:"max"`
+	Format    string   `mapstructure:"format"`
+	Choices   []string `mapstructure:"choices"`
+	match     *regexp.Regexp
+}
+
+type FormFiller struct {
+	Rules []*FormRule
+	Rand  *rand.Rand
+	mu    sync.Mutex // Rand is not safe for concurrent use
+}
+
+func LoadFormRules(filename string) (rules []*FormRule, err error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read form rules:%s:%v", filename, err)
+	}
+	if err := v.UnmarshalKey("rule", &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal form rules:%s:%v", filename, err)
+	}
+	return rules, nil
+}
+
+func NewFormFiller(rules []*FormRule) (*FormFiller, error) {
+	for _, rule := range rules {
+		switch rule.Generator {
+		case "", GeneratorEMAIL, GeneratorDATE, GeneratorCHOICE:
+		case GeneratorINT:
+			if rule.Max < rule.Min {
+				return nil, fmt.Errorf("invalid int range:%d:%d", rule.Min, rule.Max)
+			}
+		default:
+			return nil, fmt.Errorf("not supported generator:%s", rule.Generator)
+		}
+		if rule.Match != "" {
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid match:%s:%v", rule.Match, err)
+			}
+			rule.match = re
+		}
+	}
+	return &FormFiller{
+		Rules: rules,
+		Rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+func (rule *FormRule) Matches(field *FormField) bool {
+	return (rule.Name == "" || rule.Name == field.Name) &&
+		(rule.Type == "" || rule.Type == field.Type) &&
+		(rule.match == nil || rule.match.MatchString(field.Name))
+}
+
+func (ff *FormFiller) generate(rule *FormRule, field *FormField) string {
+	switch rule.Generator {
+	case GeneratorEMAIL:
+		domain := rule.Value
+		if domain == "" {
+			domain = "example.com"
+		}
+		return fmt.Sprintf("goscraper%d@%s", ff.Rand.Intn(100000), domain)
+	case GeneratorINT:
+		return fmt.Sprintf("%d", rule.Min+ff.Rand.Intn(rule.Max-rule.Min+1))
+	case GeneratorDATE:
+		format := rule.Format
+		if format == "" {
+			format = "2006-01-02"
+		}
+		days := rule.Min
+		if rule.Max > rule.Min {
+			days += ff.Rand.Intn(rule.Max - rule.Min + 1)
+		}
+		return time.Now().AddDate(0, 0, days).Format(format)
+	case GeneratorCHOICE:
+		choices := rule.Choices
+		if len(choices) == 0 {
+			for _, o := range field.Options {
+				choices = append(choices, o.Value)
+			}
+		}
+		if len(choices) == 0 {
+			return rule.Value
+		}
+		return choices[ff.Rand.Intn(len(choices))]
+	default:
+		return rule.Value
+	}
+}
+
+// Fill returns the default values of form, overwritten by the first rule
+// matching each field. Radio buttons are filled once per name.
+func (ff *FormFiller) Fill(form *Form) url.Values {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	values := form.DefaultValues()
+	filled := make(map[string]bool)
+	for _, field := range form.Fields {
+		if field.Name == "" || field.Disabled || filled[field.Name] {
+			continue
+		}
+		for _, rule := range ff.Rules {
+			if rule.Matches(field) {
+				values.Set(field.Name, ff.generate(rule, field))
+				filled[field.Name] = true
+				break
+			}
+		}
+	}
+	return values
+}
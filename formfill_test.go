@@ -0,0 +1,53 @@
+package goscraper
+
+import (
+	"math/rand"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestFormFiller(t *testing.T) {
+	rules, err := LoadFormRules(filepath.Join("testdata", "formrules.toml"))
+	if err != nil {
+		t.Fatalf("error in LoadFormRules:%v", err)
+	}
+	ff, err := NewFormFiller(rules)
+	if err != nil {
+		t.Fatalf("error in NewFormFiller:%v", err)
+	}
+	ff.Rand = rand.New(rand.NewSource(1))
+
+	form := &Form{
+		Fields: []*FormField{
+			{Name: "csrf", Type: "hidden", Value: "token"},
+			{Name: "mail_address", Type: "text"},
+			{Name: "age", Type: "number"},
+			{Name: "since", Type: "date"},
+			{Name: "color", Type: "select", Options: []*FormOption{{Value: "red"}, {Value: "blue"}}},
+			{Name: "title", Type: "text"},
+		},
+	}
+	values := ff.Fill(form)
+
+	if values.Get("csrf") != "token" || values.Get("title") != "goscraper" {
+		t.Errorf("not matched fixed values: %v", values)
+	}
+	if !regexp.MustCompile(`^goscraper\d+@example\.com$`).MatchString(values.Get("mail_address")) {
+		t.Errorf("not matched email: %v", values.Get("mail_address"))
+	}
+	if age, err := strconv.Atoi(values.Get("age")); err != nil || age < 1 || age > 10 {
+		t.Errorf("not matched int: %v", values.Get("age"))
+	}
+	if !regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`).MatchString(values.Get("since")) {
+		t.Errorf("not matched date: %v", values.Get("since"))
+	}
+	if color := values.Get("color"); color != "red" && color != "blue" {
+		t.Errorf("not matched choice: %v", color)
+	}
+
+	if _, err := NewFormFiller([]*FormRule{{Generator: "unknown"}}); err == nil {
+		t.Errorf("no error in unknown generator")
+	}
+}
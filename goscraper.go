@@ -1,8 +1,8 @@
 package goscraper
 
 import (
+	"context"
 	"database/sql"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,6 +20,8 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/gocolly/colly"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -40,6 +43,10 @@ const (
 	OptOUTTYPE       = "outtype"
 	OptOUTPUTCSV     = "csv"
 	OptOUTPUTJSON    = "json"
+	OptOUTPUTMD      = "md"
+	OptOUTPUTJSONL   = "jsonl"
+	OptOUTPUTHTML    = "html"
+	OptOUTPUTXML     = "xml"
 	OptOUTFILE       = "outfile"
 	OptDISURLFILTER  = "disurlfilter"
 	OptURLFILTER     = "urlfilter"
@@ -51,6 +58,63 @@ const (
 	OptLINKSELECTOR  = "linkselector"
 	OptISDOPOST      = "isdopost"
 	OptCHECKLOGIN    = "checklogin"
+	OptSIMILARITY    = "similarity"
+	OptSIGNIFKEYS    = "signifkeys"
+	OptORDER         = "order"
+	OrderSORTED      = "sorted"
+	OrderDISCOVERY   = "discovery"
+	OptBROKENTYPE    = "brokentype"
+	OptFAILONBROKEN  = "failonbroken"
+	OptFORMRULES     = "formrules"
+	OptAUTH          = "auth"
+	OptLOGINPAGE     = "loginpage"
+	OptAUTHTOKEN     = "authtoken"
+	OptAUTHHEADERS   = "authheaders"
+	OptAUTHHOSTS     = "authhosts"
+	OptCOOKIEFILE    = "cookiefile"
+	OptSESSIONCHECK  = "sessioncheck"
+	OptMAXRELOGIN    = "maxrelogin"
+	OptSAFETY        = "safety"
+	OptSAFETYRULES   = "safetyrules"
+	OptSTATEFILE     = "statefile"
+	OptRESUME        = "resume"
+	OptSTREAM        = "stream"
+	OptCOLLAPSE      = "collapse"
+	OptDIFFTYPE      = "difftype"
+	OptINPUT         = "input"
+)
+
+// concurrency options of crawl
+const (
+	OptPARALLELISM       = "parallelism"
+	OptDOMAINPARALLELISM = "domainparallelism"
+	OptDELAY             = "delay"
+	OptRANDOMDELAY       = "randomdelay"
+)
+
+// politeness options of crawl
+const (
+	OptRATELIMIT      = "ratelimit"
+	OptBURST          = "burst"
+	OptROBOTS         = "robots"
+	OptBACKOFF        = "backoff"
+	OptMAXBACKOFF     = "maxbackoff"
+	OptBACKOFFRETRIES = "backoffretries"
+	OptBUDGET         = "budget"
+)
+
+// retry options of crawl
+const (
+	OptMAXRETRIES    = "maxretries"
+	OptRETRYDELAY    = "retrydelay"
+	OptMAXRETRYDELAY = "maxretrydelay"
+)
+
+// canonicalization options of crawl
+const (
+	OptKEEPFRAGMENT = "keepfragment"
+	OptTRIMSLASH    = "trimslash"
+	OptSTRIPPARAMS  = "stripparams"
 )
 
 var FormTypeBtn = map[string]bool{
@@ -71,7 +135,87 @@ type Link struct {
 	Selector    string  `json:"selector"`
 }
 
-type Links map[Link]bool
+type LinkInfo struct {
+	Seq          int           `json:"seq"`
+	Depth        int           `json:"depth"`
+	StatusCode   int           `json:"status_code"`
+	ContentType  string        `json:"content_type"`
+	Error        string        `json:"error,omitempty"`
+	FirstSeen    time.Time     `json:"first_seen"`
+	LastSeen     time.Time     `json:"last_seen"`
+	SeenCount    int           `json:"seen_count"`
+	ResponseTime time.Duration `json:"response_time"`
+	Form         *Form         `json:"form,omitempty"`
+	Skipped      string        `json:"skipped_reason,omitempty"`
+	Attempts     int           `json:"attempts,omitempty"`
+	Outcome      string        `json:"outcome,omitempty"`
+	ErrorClass   string        `json:"error_class,omitempty"`
+}
+
+func (info *LinkInfo) setTarget(res *LinkInfo) {
+	info.StatusCode = res.StatusCode
+	info.ContentType = res.ContentType
+	info.Error = res.Error
+	info.ResponseTime = res.ResponseTime
+	info.Attempts = res.Attempts
+	info.Outcome = res.Outcome
+	info.ErrorClass = res.ErrorClass
+}
+
+type LinkRecord struct {
+	Link
+	*LinkInfo
+}
+
+type Links map[Link]*LinkInfo
+
+func (info *LinkInfo) seq() int {
+	if info == nil {
+		return 0
+	}
+	return info.Seq
+}
+
+func SortLinks(links Links, order string) ([]Link, error) {
+	switch order {
+	case "", OrderSORTED:
+		return sortedLinks(links), nil
+	case OrderDISCOVERY:
+		ls := sortedLinks(links)
+		sort.SliceStable(ls, func(i, j int) bool {
+			return links[ls[i]].seq() < links[ls[j]].seq()
+		})
+		return ls, nil
+	default:
+		return nil, fmt.Errorf("not supported order:%s", order)
+	}
+}
+
+func sortedLinks(links Links) []Link {
+	type sortKey struct {
+		link   Link
+		fields [8]string
+	}
+	keys := make([]sortKey, 0, len(links))
+	for l := range links {
+		keys = append(keys, sortKey{link: l, fields: [8]string{
+			l.From.String(), l.To.String(), l.Method, l.Tag, l.Selector, l.AttrId, l.AttrOnClick, l.Text,
+		}})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		for f := range keys[i].fields {
+			if keys[i].fields[f] != keys[j].fields[f] {
+				return keys[i].fields[f] < keys[j].fields[f]
+			}
+		}
+		return false
+	})
+	ls := make([]Link, len(keys))
+	for i, k := range keys {
+		ls[i] = k.link
+	}
+	return ls
+}
 
 type LinkScraper struct {
 	Collector    *colly.Collector
@@ -79,13 +223,42 @@ type LinkScraper struct {
 	Logger       log.Logger
 	LoginURL     string
 	LoginData    map[string]string
+	Auth         Authenticator
 	Entry        string
 	OutFile      string
 	OutType      string
+	Order        string
 	LinkSelector string
 	IsDoPost     bool
 	CheckLogin   string
+	Similarity   URLSimilarity
+	BrokenType   string
+	Errors       *ErrorCollector
+	FormFiller   *FormFiller
+	Session      SessionCheck
+	MaxRelogin   int
+	Relogins     metrics.Counter
+	Safety       *SafetyGuard
+	State        *CrawlState
+	Sink         LinkSink
+	Stream       bool
+	Store        LinkStore
 	URLs         []*url.URL
+	tracker      *targetTracker
+	session      *sessionState
+	stream       *linkStream
+	ctx          context.Context
+
+	// Parallelism is the max requests in flight, making Collector async if
+	// more than 1. DomainParallelism is the max per allowed domain instead.
+	Parallelism       int
+	DomainParallelism int
+	Delay             time.Duration
+	RandomDelay       time.Duration
+
+	Politeness    *Politeness
+	Retry         *RetryPolicy
+	Canonicalizer *Canonicalizer
 }
 
 type Config struct {
@@ -94,12 +267,38 @@ type Config struct {
 	Collector    *colly.Collector
 	LoginURL     string
 	LoginData    map[string]string
+	Auth         Authenticator
 	Entry        string
 	OutFile      string
 	OutType      string
+	Order        string
 	LinkSelector string
 	IsDoPost     bool
 	CheckLogin   string
+	Similarity   URLSimilarity
+	BrokenType   string
+	FormFiller   *FormFiller
+	Session      SessionCheck
+	MaxRelogin   int
+	Relogins     metrics.Counter
+	Safety       *SafetyGuard
+	State        *CrawlState
+	Sink         LinkSink
+	Stream       bool
+	Store        LinkStore
+	// Parallelism, DomainParallelism, Delay and RandomDelay are applied to
+	// Collector as LimitRules if set.
+	Parallelism       int
+	DomainParallelism int
+	Delay             time.Duration
+	RandomDelay       time.Duration
+	// Politeness throttles requests per host, no throttle if nil.
+	Politeness *Politeness
+	// Retry retries requests failed by transient errors, no retry if nil.
+	Retry *RetryPolicy
+	// Canonicalizer rewrites link URLs to dedupe, DefaultCanonicalizer if
+	// nil. The URLs are visited as found.
+	Canonicalizer *Canonicalizer
 }
 
 func NewLinkScraper(config *Config) (*LinkScraper, error) {
@@ -111,19 +310,42 @@ func NewLinkScraper(config *Config) (*LinkScraper, error) {
 		cfg = config
 	}
 
-	return &LinkScraper{
+	checkLogin := func() string {
+		if cfg.CheckLogin == "" {
+			return "loggedin"
+		}
+		return cfg.CheckLogin
+	}()
+
+	loginData := func() map[string]string {
+		if cfg.LoginData == nil {
+			return make(map[string]string)
+		}
+		return cfg.LoginData
+	}()
+
+	links := func() Links {
+		if cfg.Links == nil {
+			return make(Links)
+		}
+		return cfg.Links
+	}()
+
+	store := func() LinkStore {
+		if cfg.Store == nil {
+			return NewMapLinkStore(links)
+		}
+		return cfg.Store
+	}()
+
+	ls := &LinkScraper{
 		Collector: func() *colly.Collector {
 			if cfg.Collector == nil {
 				return colly.NewCollector() // plain colly collector.
 			}
 			return cfg.Collector
 		}(),
-		Links: func() Links {
-			if cfg.Links == nil {
-				return make(Links)
-			}
-			return cfg.Links
-		}(),
+		Links: links,
 		Logger: func() log.Logger {
 			if cfg.Logger == nil {
 				w := log.NewSyncWriter(os.Stderr)
@@ -132,12 +354,13 @@ func NewLinkScraper(config *Config) (*LinkScraper, error) {
 			}
 			return cfg.Logger
 		}(),
-		LoginURL: cfg.LoginURL,
-		LoginData: func() map[string]string {
-			if cfg.LoginData == nil {
-				return make(map[string]string)
+		LoginURL:  cfg.LoginURL,
+		LoginData: loginData,
+		Auth: func() Authenticator {
+			if cfg.Auth == nil && cfg.LoginURL != "" {
+				return &PostAuthenticator{LoginURL: cfg.LoginURL, Data: loginData}
 			}
-			return cfg.LoginData
+			return cfg.Auth
 		}(),
 		Entry: cfg.Entry,
 		OutFile: func() string {
@@ -152,21 +375,116 @@ func NewLinkScraper(config *Config) (*LinkScraper, error) {
 			}
 			return cfg.OutType
 		}(),
+		Order: func() string {
+			if cfg.Order == "" {
+				return OrderSORTED
+			}
+			return cfg.Order
+		}(),
 		LinkSelector: func() string {
 			if cfg.LinkSelector == "" {
 				return "a[href],form,[onclick]"
 			}
 			return cfg.LinkSelector
 		}(),
-		IsDoPost: cfg.IsDoPost,
-		CheckLogin: func() string {
-			if cfg.CheckLogin == "" {
-				return "loggedin"
+		IsDoPost:   cfg.IsDoPost,
+		CheckLogin: checkLogin,
+		Similarity: func() URLSimilarity {
+			if cfg.Similarity == nil {
+				return DefaultURLSimilarity
 			}
-			return cfg.CheckLogin
+			return cfg.Similarity
 		}(),
-		URLs: make([]*url.URL, 0),
-	}, nil
+		BrokenType: cfg.BrokenType,
+		Errors:     NewErrorCollector(),
+		FormFiller: cfg.FormFiller,
+		Session:    cfg.Session,
+		MaxRelogin: func() int {
+			if cfg.MaxRelogin == 0 {
+				return 3
+			}
+			return cfg.MaxRelogin
+		}(),
+		Relogins: func() metrics.Counter {
+			if cfg.Relogins == nil {
+				return discard.NewCounter()
+			}
+			return cfg.Relogins
+		}(),
+		Safety:  cfg.Safety,
+		State:   cfg.State,
+		Sink:    cfg.Sink,
+		Stream:  cfg.Stream,
+		Store:   store,
+		URLs:    make([]*url.URL, 0),
+		tracker: newTargetTracker(store),
+		session: newSessionState(),
+
+		Parallelism:       cfg.Parallelism,
+		DomainParallelism: cfg.DomainParallelism,
+		Delay:             cfg.Delay,
+		RandomDelay:       cfg.RandomDelay,
+
+		Politeness: cfg.Politeness,
+		Retry:      cfg.Retry,
+		Canonicalizer: func() *Canonicalizer {
+			if cfg.Canonicalizer == nil {
+				return DefaultCanonicalizer
+			}
+			return cfg.Canonicalizer
+		}(),
+	}
+	switch a := ls.Auth.(type) {
+	case *HeaderAuthenticator:
+		if len(a.Hosts) == 0 {
+			a.Hosts = ls.authHosts()
+		}
+	case *DigestAuthenticator:
+		if len(a.Hosts) == 0 {
+			a.Hosts = ls.authHosts()
+		}
+	}
+	if err := ls.limit(); err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+// limit sets LimitRules of the concurrency and delay to Collector, with a
+// rule per allowed domain if DomainParallelism is set and a rule for the
+// rest, as colly applies the first matched rule.
+func (ls *LinkScraper) limit() error {
+	if ls.Parallelism <= 1 && ls.DomainParallelism <= 1 && ls.Delay == 0 && ls.RandomDelay == 0 {
+		return nil
+	}
+	rule := func(glob string, parallelism int) *colly.LimitRule {
+		if parallelism < 1 {
+			parallelism = 1
+		}
+		return &colly.LimitRule{DomainGlob: glob, Parallelism: parallelism, Delay: ls.Delay, RandomDelay: ls.RandomDelay}
+	}
+	var rules []*colly.LimitRule
+	if ls.DomainParallelism > 0 {
+		for _, domain := range ls.Collector.AllowedDomains {
+			rules = append(rules, rule(domain, ls.DomainParallelism))
+		}
+	}
+	rules = append(rules, rule("*", ls.Parallelism))
+	if err := ls.Collector.Limits(rules); err != nil {
+		return fmt.Errorf("failed to set limit rules:%v", err)
+	}
+	ls.Collector.Async = ls.Parallelism > 1 || ls.DomainParallelism > 1
+	return nil
+}
+
+// authHosts are the hosts of Entry and LoginURL, to send credentials to.
+func (ls *LinkScraper) authHosts() (hosts []string) {
+	for _, raw := range []string{ls.Entry, ls.LoginURL} {
+		if u, err := url.Parse(raw); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return hosts
 }
 
 func DefaultLinkScraper() *LinkScraper {
@@ -174,80 +492,316 @@ func DefaultLinkScraper() *LinkScraper {
 }
 
 func (ls *LinkScraper) Scrape() (err error) {
+	return ls.ScrapeContext(context.Background())
+}
 
+// ScrapeContext is Scrape, but stops visiting new links when ctx is done.
+// Requests in flight are finished, and the links found so far are output
+// before ctx.Err() is returned.
+func (ls *LinkScraper) ScrapeContext(ctx context.Context) (err error) {
+	ls.ctx = ctx
 	ls.registHandler()
+	if ls.Stream {
+		if err := ls.openSink(); err != nil {
+			level.Error(ls.Logger).Log("msg", "failed to open output", "error", err)
+			return err
+		}
+	}
+	var pending []*StateRequest
+	if ls.State != nil {
+		pending, err = ls.restore()
+		if err != nil {
+			level.Error(ls.Logger).Log("msg", "failed to restore state", "error", err)
+			return err
+		}
+	}
 	ls.Login()
-	ls.Collector.Visit(ls.Entry)
+	ls.Collector.Wait()
+	if ls.State.Resumed() {
+		level.Info(ls.Logger).Log("msg", "resume", "links", ls.Store.Len(), "pending", len(pending))
+		for _, r := range pending {
+			if ls.stopped() {
+				break
+			}
+			if err := r.Do(ls.Collector); err != nil {
+				level.Debug(ls.Logger).Log("msg", "not resumed", "url", r.URL, "error", err)
+			}
+		}
+	} else {
+		ls.State.Queue(entryRequest(ls.Entry))
+		ls.Collector.Visit(ls.Entry)
+	}
+	ls.Collector.Wait()
+	ls.Links = ls.Store.Links()
+	if ls.stopped() {
+		level.Warn(ls.Logger).Log("msg", "stopped scrape", "links", len(ls.Links), "error", ctx.Err())
+	}
 
 	err = ls.Output()
 	if err != nil {
 		level.Error(ls.Logger).Log("msg", "failed to output", "error", err)
 		return err
 	}
-	return nil
+	if ls.BrokenType != "" {
+		err = ls.OutputBrokenLinks()
+		if err != nil {
+			level.Error(ls.Logger).Log("msg", "failed to output broken links", "error", err)
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (ls *LinkScraper) stopped() bool {
+	return ls.ctx != nil && ls.ctx.Err() != nil
+}
+
+// restore loads the links and results of the last run from State, and
+// returns the requests it left.
+func (ls *LinkScraper) restore() (pending []*StateRequest, err error) {
+	if err := ls.Collector.SetStorage(ls.State); err != nil {
+		return nil, fmt.Errorf("failed to set state storage:%v", err)
+	}
+	for key, res := range ls.State.Results() {
+		ls.tracker.restore(key, res)
+		ls.Errors.restore(key, res)
+	}
+	for link, info := range ls.State.Links() {
+		l := link
+		ls.Store.Put(l, info)
+		ls.tracker.track(&l, &l.To)
+	}
+	return ls.State.Pending(), nil
 }
 
 func (ls *LinkScraper) registHandler() {
+	if ls.Store == nil {
+		ls.Store = NewMapLinkStore(ls.Links)
+	}
+	if ls.tracker == nil {
+		ls.tracker = newTargetTracker(ls.Store)
+	}
+	if ls.Errors == nil {
+		ls.Errors = NewErrorCollector()
+	}
+	// failed URLs by the keys of the links
+	ls.Errors.Canonicalizer = ls.Canonicalizer
+	if ls.session == nil {
+		ls.session = newSessionState()
+	}
+	if ls.Relogins == nil {
+		ls.Relogins = discard.NewCounter()
+	}
+
 	ls.Collector.OnRequest(func(r *colly.Request) {
+		resumeDepth(r)
 		level.Debug(ls.Logger).Log("msg", "requesting...", "url", r.URL.String(), "method", r.Method)
-		r.Ctx.Put("url", r.URL.String())
+		if err := ls.Retry.Wait(ls.ctx, r); err != nil {
+			level.Debug(ls.Logger).Log("msg", "stopped waiting retry", "url", r.URL.String(), "error", err)
+		}
+		if err := ls.Politeness.Wait(ls.ctx, r.URL); err != nil {
+			level.Debug(ls.Logger).Log("msg", "stopped waiting", "url", r.URL.String(), "error", err)
+		}
+		ls.tracker.start(r)
+	})
+
+	ls.Collector.OnResponse(func(r *colly.Response) {
+		level.Debug(ls.Logger).Log("msg", "response", "url", r.Request.URL.String(), "status", r.StatusCode)
+		ls.tracker.finish(r, nil)
+		ls.Errors.Collect(r, nil)
+		// not images, css nor scripts, which have no session marker
+		if strings.Contains(r.Headers.Get("Content-Type"), "html") {
+			ls.checkSession(r)
+		}
+	})
+
+	ls.Collector.OnError(func(r *colly.Response, err error) {
+		if ls.backOff(r) || ls.retry(r, err) {
+			return
+		}
+		level.Warn(ls.Logger).Log("msg", "failed request", "url", r.Request.URL.String(), "status", r.StatusCode, "error", err)
+		ls.State.Done(r.Request, ls.tracker.finish(r, err))
+		ls.Errors.Collect(r, err)
+		// not 404 and the like, but an expired session denied
+		if r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden {
+			ls.checkSession(r)
+		}
+	})
+
+	ls.Collector.OnScraped(func(r *colly.Response) {
+		ls.session.done(r.Request)
+		ls.State.Done(r.Request, ls.tracker.result(r.Request.URL))
 	})
 
 	ls.Collector.OnHTML(ls.LinkSelector, func(e *colly.HTMLElement) {
-		link, err := E2Link(e)
+		if ls.session.isLoggedOut(e.Request) {
+			return
+		}
+		found, err := E2Link(e)
 		if err != nil {
 			level.Error(ls.Logger).Log("msg", "failed to create link", "error", err)
+			return
 		}
-		link.Selector = ls.LinkSelector
+		found.Selector = ls.LinkSelector
+		// canonical to dedupe, but the found URL is visited as it is
+		link := ls.Canonicalizer.Link(found)
 		LogLink(level.Error(ls.Logger), "found link", link)
-		if _, ok := Add(ls.Links, link); ok {
-			level.Debug(ls.Logger).Log("msg", "added link", "link", link)
-			if ls.IsDoPost && link.Method == http.MethodPost {
-				param := make(map[string]string)
-				e.ForEach("input", func(_ int, ce *colly.HTMLElement) {
-					if !FormTypeBtn[ce.Attr("type")] {
-						param[ce.Attr("name")] = ce.Attr("value")
-					}
-				})
-				level.Debug(ls.Logger).Log("msg", "post", "url", link.To.String(), "param", fmt.Sprintf("%v", param))
-				if !ls.IsLogin(e) {
-					ls.LoginE(e)
+		if !ls.Store.Add(link) {
+			LogLink(level.Debug(ls.Logger), "already exists in links", link)
+			return
+		}
+		level.Debug(ls.Logger).Log("msg", "added link", "link", link)
+		var form *Form
+		if e.Name == "form" {
+			form, err = E2Form(e)
+			if err != nil {
+				level.Error(ls.Logger).Log("msg", "failed to create form", "error", err)
+			}
+		}
+		reason, denied := ls.Safety.Deny(found, form)
+		if !denied {
+			reason, denied = ls.polite(&found.To)
+		}
+		ls.Store.Update(*link, func(info *LinkInfo) {
+			info.Depth = e.Request.Depth
+			info.Form = form
+			info.Skipped = reason
+		})
+		ls.tracker.track(link, &found.To)
+		info, _ := ls.Store.Get(*link)
+		ls.State.SaveLink(link, &info)
+		if ls.Stream {
+			ls.streamLink(link, &info)
+		}
+		if denied {
+			LogLink(level.Info(log.With(ls.Logger, "reason", reason)), "skipped link", link)
+			return
+		}
+		if ls.IsDoPost && link.Method == http.MethodPost && form != nil {
+			values := form.DefaultValues()
+			if ls.FormFiller != nil {
+				values = ls.FormFiller.Fill(form)
+			}
+			for _, sub := range form.Submissions(values) {
+				level.Debug(ls.Logger).Log("msg", "post", "url", sub.URL, "method", sub.Method, "param", sub.Values.Encode())
+				ls.State.Queue(&StateRequest{Method: sub.Method, URL: sub.URL, Depth: e.Request.Depth + 1, Form: sub})
+				if ls.stopped() {
+					continue
 				}
-				e.Request.Post(link.To.String(), param)
+				if err := sub.Submit(e.Request); err != nil {
+					level.Debug(ls.Logger).Log("msg", "failed to post", "url", sub.URL, "error", err)
+				}
+			}
+			return
+		}
+		if !strings.HasPrefix(strings.TrimSpace(found.To.String()), "javascript:") {
+			if ls.State.IsDone(http.MethodGet, &found.To) {
+				LogLink(level.Debug(ls.Logger), "visited in last run", link)
 				return
 			}
-			if !strings.HasPrefix(strings.TrimSpace(link.To.String()), "javascript:") {
-				level.Debug(ls.Logger).Log("msg", "visit", "url", e.Request.AbsoluteURL(link.To.String()))
-				if !ls.IsLogin(e) {
-					ls.LoginE(e)
-				}
-				e.Request.Visit(link.To.String())
+			level.Debug(ls.Logger).Log("msg", "visit", "url", found.To.String())
+			ls.State.Queue(&StateRequest{Method: http.MethodGet, URL: found.To.String(), Depth: e.Request.Depth + 1})
+			if ls.stopped() {
+				// left in State to visit on resume
 				return
 			}
-			LogLink(level.Debug(ls.Logger), "not visited link", link)
-			return
-		} else {
-			LogLink(level.Debug(ls.Logger), "already exists in links", link)
+			if err := e.Request.Visit(found.To.String()); err != nil {
+				level.Debug(ls.Logger).Log("msg", "not visited", "url", found.To.String(), "error", err)
+			}
 			return
 		}
+		LogLink(level.Debug(ls.Logger), "not visited link", link)
 	})
 }
 
+// polite returns the reason not to request u by Politeness, only for the
+// urls Collector may visit, not to fetch robots.txt of other hosts.
+func (ls *LinkScraper) polite(u *url.URL) (reason string, denied bool) {
+	if ls.Politeness == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	if len(ls.Collector.AllowedDomains) > 0 {
+		allowed := false
+		for _, domain := range ls.Collector.AllowedDomains {
+			allowed = allowed || domain == u.Host
+		}
+		if !allowed {
+			return "", false
+		}
+	}
+	return ls.Politeness.Deny(u)
+}
+
+// backOff retries r.Request after 429 or 503, once the host is allowed
+// again by Politeness.
+func (ls *LinkScraper) backOff(r *colly.Response) bool {
+	wait, retry := ls.Politeness.BackOff(r)
+	if !retry || ls.stopped() {
+		return false
+	}
+	level.Warn(ls.Logger).Log("msg", "backing off", "url", r.Request.URL.String(), "status", r.StatusCode, "wait", wait)
+	ls.tracker.finish(r, nil)
+	ls.tracker.retry(r.Request)
+	// the error of the retry itself in sync mode, handled by its callbacks
+	if err := r.Request.Retry(); err != nil {
+		level.Debug(ls.Logger).Log("msg", "failed retry", "url", r.Request.URL.String(), "error", err)
+	}
+	return true
+}
+
+// retry retries r.Request failed by a transient err, which waits the delay
+// of Retry in OnRequest.
+func (ls *LinkScraper) retry(r *colly.Response, err error) bool {
+	if ls.stopped() {
+		return false
+	}
+	delay, ok := ls.Retry.Next(r, err)
+	if !ok {
+		return false
+	}
+	level.Warn(ls.Logger).Log("msg", "retrying", "url", r.Request.URL.String(), "status", r.StatusCode, "delay", delay, "error", err)
+	ls.tracker.finish(r, err)
+	ls.tracker.retry(r.Request)
+	if err := r.Request.Retry(); err != nil {
+		level.Debug(ls.Logger).Log("msg", "failed retry", "url", r.Request.URL.String(), "error", err)
+	}
+	return true
+}
+
 func Add(links Links, link *Link) (res Links, ok bool) {
 	switch {
 	case link.From.String() == link.To.String():
 		return links, false
-	case links[*link]:
+	case links[*link] != nil:
+		info := links[*link]
+		info.LastSeen = time.Now()
+		info.SeenCount++
 		return links, false
 	default:
-		links[*link] = true
+		now := time.Now()
+		links[*link] = &LinkInfo{
+			Seq:       len(links) + 1,
+			FirstSeen: now,
+			LastSeen:  now,
+			SeenCount: 1,
+		}
 		return links, true
 	}
 }
 
 func (ls *LinkScraper) Login() (err error) {
+	return ls.login(ls.Collector)
+}
 
-	err = ls.Collector.Post(ls.LoginURL, ls.LoginData)
+func (ls *LinkScraper) login(c *colly.Collector) (err error) {
+	if ls.Auth == nil {
+		return nil
+	}
+	if ls.session != nil {
+		ls.session.setInLogin(true)
+		defer ls.session.setInLogin(false)
+	}
+	err = ls.Auth.Authenticate(c)
 	if err != nil {
 		level.Error(ls.Logger).Log("msg", "failed login", "error", err)
 		return err
@@ -256,21 +810,68 @@ func (ls *LinkScraper) Login() (err error) {
 }
 
 func (ls *LinkScraper) LoginE(e *colly.HTMLElement) (err error) {
-	e.Request.Post(ls.LoginURL, ls.LoginData)
-	return nil
+	return ls.Relogin()
 }
 
 func (ls *LinkScraper) IsLogin(e *colly.HTMLElement) bool {
-	if strings.Index(string(e.Response.Body), ls.CheckLogin) > -1 {
-		return true
+	if ls.Session == nil {
+		return strings.Index(string(e.Response.Body), ls.CheckLogin) > -1
+	}
+	return ls.Session.IsLoggedIn(ls.Collector, e.Response)
+}
+
+// Relogin logs in again synchronously, at most MaxRelogin times per crawl.
+func (ls *LinkScraper) Relogin() (err error) {
+	if !ls.session.begin(ls.MaxRelogin) {
+		return fmt.Errorf("exceeded max relogin:%d", ls.MaxRelogin)
+	}
+	ls.Relogins.Add(1)
+	level.Info(ls.Logger).Log("msg", "relogin", "count", ls.session.count())
+	// a clone sharing cookies, which posts LoginURL again regardless of
+	// visited, and is sync as waiting for Collector in a callback never ends
+	c := ls.Collector.Clone()
+	c.Async = false
+	c.AllowURLRevisit = true
+	return ls.login(c)
+}
+
+func (ls *LinkScraper) ReloginCount() int {
+	return ls.session.count()
+}
+
+// checkSession relogins and retries an HTML page failing the Session check,
+// once per URL. The page's links are dropped only when its retry after the
+// relogin is done, and kept if the check fails again on the retry.
+func (ls *LinkScraper) checkSession(r *colly.Response) {
+	if ls.Session == nil || ls.Auth == nil || ls.session.inLogin() {
+		return
+	}
+	if ls.Session.IsLoggedIn(ls.Collector, r) {
+		return
+	}
+	if !ls.session.retry(r.Request) {
+		level.Warn(ls.Logger).Log("msg", "failed session check after relogin", "url", r.Request.URL.String())
+		return
+	}
+	level.Info(ls.Logger).Log("msg", "logged out", "url", r.Request.URL.String())
+	if ls.stopped() {
+		return
+	}
+	if err := ls.Relogin(); err != nil {
+		level.Error(ls.Logger).Log("msg", "failed to relogin", "error", err)
+		return
+	}
+	ls.session.markLoggedOut(r.Request)
+	if err := r.Request.Retry(); err != nil {
+		level.Error(ls.Logger).Log("msg", "failed to retry", "url", r.Request.URL.String(), "error", err)
 	}
-	return false
 }
 
 func E2Link(e *colly.HTMLElement) (link *Link, err error) {
-	from, err := url.Parse(e.Request.AbsoluteURL(e.Request.Ctx.Get("url")))
+	// not Ctx, which is shared with the requests of the links
+	from, err := url.Parse(e.Request.URL.String())
 	if err != nil {
-		return nil, fmt.Errorf("invalid link from:%s:%v", e.Request.Ctx.Get("url"), err)
+		return nil, fmt.Errorf("invalid link from:%s:%v", e.Request.URL, err)
 	}
 	var rawTo string
 	switch {
@@ -326,74 +927,97 @@ func MakeOutFilename(outfile, outtype string) (filename string) {
 	return fmt.Sprintf("%s_%s.%s", outfile, time.Now().Format("20060102150405"), outtype)
 }
 
-func (ls *LinkScraper) Output() (err error) {
-	filename := MakeOutFilename(ls.OutFile, ls.OutType)
-	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0600)
-	defer f.Close()
-	if err != nil {
-		return fmt.Errorf("failed to open output file:%s:%v", filename, err)
+func (ls *LinkScraper) openSink() (err error) {
+	if ls.stream != nil {
+		return nil
 	}
-	switch ls.OutType {
-	case OptOUTPUTCSV:
-		err = WriteLinks2Csv(ls.Links, f)
+	sink := ls.Sink
+	filename := ""
+	if sink == nil {
+		sink, filename, err = NewFileSink(ls.OutFile, ls.OutType)
 		if err != nil {
-			return fmt.Errorf("failed to write csv:%s:%v", f.Name(), err)
+			return err
 		}
-		level.Info(ls.Logger).Log("msg", "write output", "filename", filename)
-		return nil
-	case OptOUTPUTJSON:
-		b, err := Links2Json(ls.Links)
-		if err != nil {
-			return fmt.Errorf("failed to marshal:%v", err)
+	}
+	if s, ok := sink.(*SitemapSink); ok && len(s.Hosts) == 0 {
+		if u, err := url.Parse(ls.Entry); err == nil && u.Host != "" {
+			s.Hosts = []string{u.Host}
 		}
-		_, err = f.Write(b)
-		if err != nil {
-			return fmt.Errorf("failed to write json:%s:%v", f.Name(), err)
+	}
+	level.Info(ls.Logger).Log("msg", "open output", "filename", filename)
+	ls.stream = newLinkStream(sink)
+	return nil
+}
+
+func (ls *LinkScraper) streamLink(link *Link, info *LinkInfo) {
+	if err := ls.stream.write(*link, info); err != nil {
+		level.Error(ls.Logger).Log("msg", "failed to write link", "error", err)
+	}
+}
+
+// Output writes the links in Order, but the streamed ones not changed since,
+// and closes the sink.
+func (ls *LinkScraper) Output() (err error) {
+	if err := ls.openSink(); err != nil {
+		return err
+	}
+	sorted, err := SortLinks(ls.Links, ls.Order)
+	if err != nil {
+		return err
+	}
+	for _, l := range sorted {
+		if err := ls.stream.write(l, ls.Links[l]); err != nil {
+			return err
 		}
-		level.Info(ls.Logger).Log("msg", "write output", "filename", filename)
-		return nil
-	default:
-		return fmt.Errorf("not supported type:%s", ls.OutType)
 	}
+	if err := ls.stream.close(); err != nil {
+		return err
+	}
+	ls.stream = nil
+	level.Info(ls.Logger).Log("msg", "write output", "links", len(sorted))
 	return nil
 }
 
 func Links2Json(links Links) (b []byte, err error) {
-	v := []Link{}
-	for link, _ := range links {
-		v = append(v, link)
+	return Links2JsonOrder(links, OrderSORTED)
+}
+
+func Links2JsonOrder(links Links, order string) (b []byte, err error) {
+	sorted, err := SortLinks(links, order)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]LinkRecord, len(sorted))
+	for i, l := range sorted {
+		v[i] = LinkRecord{Link: l, LinkInfo: links[l]}
 	}
 	b, err = json.Marshal(v)
 	return b, err
 }
 
 func WriteLinks2Csv(links Links, w io.Writer) (err error) {
-	cw := csv.NewWriter(w)
-	cw.Write([]string{
-		"no",
-		"from",
-		"to",
-		"onclick",
-		"method",
-	})
-	i := 0
-	for k, _ := range links {
-		i++
-		if err := cw.Write([]string{
-			fmt.Sprintf("%d", i),
-			k.From.String(),
-			k.To.String(),
-			k.AttrOnClick,
-			k.Method,
-		}); err != nil {
-			return fmt.Errorf("failed to write csv record:%v", err)
-		}
-	}
-	cw.Flush()
-	if err := cw.Error(); err != nil {
-		return fmt.Errorf("failed to write csv:%v", err)
+	return WriteLinks2CsvOrder(links, w, OrderSORTED)
+}
+
+func WriteLinks2CsvOrder(links Links, w io.Writer, order string) (err error) {
+	sorted, err := SortLinks(links, order)
+	if err != nil {
+		return err
 	}
-	return nil
+	sink := NewCsvSink(struct{ io.Writer }{w})
+	for _, k := range sorted {
+		if err := sink.WriteLink(k, links[k]); err != nil {
+			return err
+		}
+	}
+	return sink.Close()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
 }
 
 func Str2filters(str, sep string) (filters []*regexp.Regexp) {
@@ -420,48 +1044,79 @@ func LogLink(logger log.Logger, msg string, link *Link) {
 }
 
 func (ls *LinkScraper) FlushURLs() []*url.URL {
-	ls.URLs = UniqURL(ls.Links)
+	ls.URLs = UniqURLBy(ls.Links, ls.Similarity)
 	return ls.URLs
 }
 
+func (ls *LinkScraper) SummaryLinks() (Links, error) {
+	return SummaryLinkBy(ls.Links, ls.Similarity)
+}
+
 func UniqURL(links Links) (urls []*url.URL) {
-	us := []url.URL{}
-	for l, _ := range links {
-		foundf := false
-		foundt := false
-		for _, u := range us {
-			if isSimilerURL(&l.From, &u) {
-				foundf = true
-			}
-			if isSimilerURL(&l.To, &u) {
-				foundt = true
+	return UniqURLBy(links, DefaultURLSimilarity)
+}
+
+// UniqURLBy returns the From and To of links not similar to the URLs of the
+// links before. From and To of a link are both looked up before adding
+// either, so both are returned if they are similar to each other only.
+func UniqURLBy(links Links, sim URLSimilarity) (urls []*url.URL) {
+	keyed, isKeyed := sim.(KeyedURLSimilarity)
+	index := make(map[string]bool)
+	found := func(u *url.URL) bool {
+		if isKeyed {
+			return index[keyed.Key(u)]
+		}
+		for _, f := range urls {
+			if sim.Similar(u, f) {
+				return true
 			}
 		}
+		return false
+	}
+	add := func(u url.URL) {
+		if isKeyed {
+			index[keyed.Key(&u)] = true
+		}
+		urls = append(urls, &u)
+	}
+	for _, l := range sortedLinks(links) {
+		foundf, foundt := found(&l.From), found(&l.To)
 		if !foundf {
-			us = append(us, l.From)
+			add(l.From)
 		}
 		if !foundt {
-			us = append(us, l.To)
+			add(l.To)
 		}
 	}
-	for i, _ := range us {
-		urls = append(urls, &us[i])
-	}
 	return urls
 }
 
 func SummaryLink(links Links) (res Links, err error) {
+	return SummaryLinkBy(links, DefaultURLSimilarity)
+}
+
+func SummaryLinkBy(links Links, sim URLSimilarity) (res Links, err error) {
 	res = make(Links)
-	for l, _ := range links {
-		res, _ = addNotSimiler(res, l)
+	keyed, isKeyed := sim.(KeyedURLSimilarity)
+	index := make(map[string]bool)
+	for _, l := range sortedLinks(links) {
+		if !isKeyed {
+			res, _ = addNotSimiler(res, l, links[l], sim)
+			continue
+		}
+		key := fmt.Sprintf("%q %q %q", keyed.Key(&l.From), keyed.Key(&l.To), l.AttrOnClick)
+		if !index[key] {
+			index[key] = true
+			res[l] = links[l]
+		}
 	}
 	return res, nil
 }
 
-func addNotSimiler(links Links, link Link) (resLinks Links, isAdded bool) {
+func addNotSimiler(links Links, link Link, info *LinkInfo, sim URLSimilarity) (resLinks Links, isAdded bool) {
 	found := false
 	for l, _ := range links {
-		if isSimilerURL(&link.From, &l.From) && isSimilerURL(&link.To, &l.To) && link.AttrOnClick == l.AttrOnClick {
+		if sim.Similar(&link.From, &l.From) && sim.Similar(&link.To, &l.To) && link.AttrOnClick == l.AttrOnClick {
 			found = true
 			break
 		}
@@ -469,7 +1124,7 @@ func addNotSimiler(links Links, link Link) (resLinks Links, isAdded bool) {
 	if found {
 		return links, false
 	} else {
-		links[link] = true
+		links[link] = info
 		return links, true
 	}
 }
@@ -496,10 +1151,12 @@ func isSimilerURL(u1, u2 *url.URL) (same bool) {
 	}
 }
 
-func SummaryURL(u1, u2 *url.URL) (map[url.URL]bool, error) {
-	// TODO: impl
-	us := map[url.URL]bool{*u1: true}
-	return us, nil
+func SummaryURL(u1, u2 *url.URL) (*URLPattern, error) {
+	p := NewURLPattern(u1)
+	if err := p.Merge(u2); err != nil {
+		return nil, fmt.Errorf("failed to summary url:%v", err)
+	}
+	return p, nil
 }
 
 type Browser struct {
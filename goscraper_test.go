@@ -1,14 +1,19 @@
 package goscraper
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"golang.org/x/text/unicode/rangetable"
 
@@ -71,8 +76,8 @@ func init() {
 	ls[3].From = ls[0].From
 	ls[3].To = ls[0].To
 
-	for _, l := range ls {
-		links[*l] = true
+	for i, l := range ls {
+		links[*l] = &LinkInfo{Seq: i + 1}
 	}
 
 	w := log.NewSyncWriter(os.Stderr)
@@ -83,10 +88,10 @@ func init() {
 
 func TestSummaryLink(t *testing.T) {
 	expectedLinks := Links{
-		*ls[0]: true,
-		*ls[1]: true,
-		*ls[2]: true,
-		*ls[3]: true,
+		*ls[0]: links[*ls[0]],
+		*ls[1]: links[*ls[1]],
+		*ls[2]: links[*ls[2]],
+		*ls[3]: links[*ls[3]],
 	}
 
 	testLinks, err := SummaryLink(links)
@@ -105,7 +110,7 @@ func TestBrowseLinks(t *testing.T) {
 	l.Text = "More information..."
 
 	blinks := Links{
-		l: true,
+		l: {Seq: 1},
 	}
 	driver, err := NewDriver()
 	if err != nil {
@@ -141,11 +146,11 @@ func TestNewDriver(t *testing.T) {
 
 func TestAdd(t *testing.T) {
 	testLinks := Links{
-		*ls[0]: true,
+		*ls[0]: {Seq: 1},
 	}
 	expect := Links{
-		*ls[0]: true,
-		*ls[1]: true,
+		*ls[0]: {Seq: 1, SeenCount: 1},
+		*ls[1]: {Seq: 2, SeenCount: 1},
 	}
 	from, _ := url.Parse("http://example.com")
 	to, _ := url.Parse("http://example.com")
@@ -162,11 +167,60 @@ func TestAdd(t *testing.T) {
 	if testLinks, ok := Add(testLinks, ls[1]); !ok {
 		t.Errorf("not added diff link: %v", testLinks)
 	}
+	if testLinks[*ls[1]].FirstSeen.IsZero() || testLinks[*ls[0]].LastSeen.IsZero() {
+		t.Errorf("not recorded seen time: %v", testLinks)
+	}
+	testLinks[*ls[0]].LastSeen = time.Time{}
+	testLinks[*ls[1]].FirstSeen = time.Time{}
+	testLinks[*ls[1]].LastSeen = time.Time{}
 	if !reflect.DeepEqual(expect, testLinks) {
 		t.Errorf("not matched,\nwant: %v,\nhave: %v", expect, testLinks)
 	}
 }
 
+func TestSortLinks(t *testing.T) {
+	testLinks := Links{
+		*ls[1]: {Seq: 1},
+		*ls[2]: {Seq: 2},
+		*ls[0]: {Seq: 3},
+	}
+	tests := map[string][]Link{
+		OrderSORTED:    {*ls[0], *ls[1], *ls[2]},
+		OrderDISCOVERY: {*ls[1], *ls[2], *ls[0]},
+	}
+	for order, expect := range tests {
+		sorted, err := SortLinks(testLinks, order)
+		if err != nil {
+			t.Errorf("error in SortLinks:%v", err)
+		}
+		if !reflect.DeepEqual(expect, sorted) {
+			t.Errorf("not matched: %s,\nwant: %v,\nhave: %v", order, expect, sorted)
+		}
+	}
+	if _, err := SortLinks(testLinks, "unknown"); err == nil {
+		t.Errorf("no error in unknown order")
+	}
+}
+
+func TestWriteLinks2Csv(t *testing.T) {
+	firstSeen := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	testLinks := Links{
+		*ls[1]: {Seq: 1, Depth: 1, StatusCode: 200, ContentType: "text/html", FirstSeen: firstSeen, LastSeen: firstSeen, SeenCount: 1, ResponseTime: time.Second, Attempts: 2, Outcome: OutcomeOK},
+		*ls[0]: {Seq: 2, StatusCode: 404, Error: "Not Found", Skipped: "deny:logout-url", Attempts: 1, Outcome: OutcomePERMANENT, ErrorClass: ClassCLIENT},
+	}
+	expect := `no,seq,from,to,attr_id,onclick,text,tag,method,selector,depth,status,content_type,error,first_seen,last_seen,seen_count,response_time,form,skipped_reason,attempts,outcome,error_class
+1,1,http://example.com,http://example.com?a1=v12,,,,,,,1,200,text/html,,2018-06-01T10:00:00Z,2018-06-01T10:00:00Z,1,1s,,,2,ok,
+2,2,http://example.com,http://example.com?a1=v11&a2=v2,,,,,,,0,404,,Not Found,,,0,0s,,deny:logout-url,1,permanent,client
+`
+	var buf bytes.Buffer
+	if err := WriteLinks2CsvOrder(testLinks, &buf, OrderDISCOVERY); err != nil {
+		t.Errorf("error in WriteLinks2CsvOrder:%v", err)
+	}
+	if buf.String() != expect {
+		t.Errorf("not matched,\nwant: %v,\nhave: %v", expect, buf.String())
+	}
+}
+
 func TestUniqURL(t *testing.T) {
 	expect := []*url.URL{
 		&ls[0].From,
@@ -174,8 +228,8 @@ func TestUniqURL(t *testing.T) {
 		&ls[1].To,
 	}
 	testLinks := Links{
-		*ls[0]: true,
-		*ls[1]: true,
+		*ls[0]: {Seq: 1},
+		*ls[1]: {Seq: 2},
 	}
 	testURLs := UniqURL(testLinks)
 	if !reflect.DeepEqual(expect, testURLs) {
@@ -196,28 +250,183 @@ func TestSummaryURL(t *testing.T) {
 			if err != nil {
 				return false
 			}
-			us, err := SummaryURL(u1, u2)
+			p, err := SummaryURL(u1, u2)
 			if err != nil {
 				return false
 			}
-			if len(us) == 1 {
-				for u, _ := range us {
-					if u.Host == u1.Host && u.Path == u1.Path && len(u.Query()) == len(u1.Query()) {
-						return true
-					}
-				}
-			}
-			return false
+			return p.Host == u1.Host && p.Match(u1) && p.Match(u2) && len(p.Query) == len(u1.Query())
 		},
 		gen.UnicodeString(uRLRT),
 		gen.UnicodeString(uRLRT),
 	))
 
+	properties.Property("numeric path segments are summaryze", prop.ForAll(
+		func(n1, n2 uint) bool {
+			u1, _ := url.Parse(fmt.Sprintf("http://example.com/users/%d/edit", n1))
+			u2, _ := url.Parse(fmt.Sprintf("http://example.com/users/%d/edit", n2))
+			p, err := SummaryURL(u1, u2)
+			if err != nil {
+				return false
+			}
+			return p.String() == "http://example.com/users/{id}/edit"
+		},
+		gen.UInt(),
+		gen.UInt(),
+	))
+
+	properties.TestingRun(t)
+
+	u1, _ := url.Parse("http://example.com/users/new")
+	u2, _ := url.Parse("http://example.com/users/123")
+	if p, err := SummaryURL(u1, u2); err == nil {
+		t.Errorf("summaryzed different path: %v", p)
+	}
+}
+
+// uniqURLBaseline is UniqURLBy before the key index, verbatim but ranging
+// over sortedLinks for a stable order.
+func uniqURLBaseline(links Links, sim URLSimilarity) (urls []*url.URL) {
+	us := []url.URL{}
+	for _, l := range sortedLinks(links) {
+		foundf := false
+		foundt := false
+		for _, u := range us {
+			if sim.Similar(&l.From, &u) {
+				foundf = true
+			}
+			if sim.Similar(&l.To, &u) {
+				foundt = true
+			}
+		}
+		if !foundf {
+			us = append(us, l.From)
+		}
+		if !foundt {
+			us = append(us, l.To)
+		}
+	}
+	for i, _ := range us {
+		urls = append(urls, &us[i])
+	}
+	return urls
+}
+
+func TestSummaryLinkIndex(t *testing.T) {
+	properties := gopter.NewProperties(nil)
+
+	properties.Property("indexed summary is same as pairwise and baseline summary", prop.ForAll(
+		func(ns []uint8) bool {
+			testLinks := make(Links)
+			for i, n := range ns {
+				from, _ := url.Parse(fmt.Sprintf("http://example.com/%d", n%3))
+				to, _ := url.Parse(fmt.Sprintf("http://example.com/%d?a%d=%d", n%5, n%2, i))
+				if n%4 == 0 {
+					// similar to From
+					to, _ = url.Parse(fmt.Sprintf("http://example.com/%d", n%3))
+				}
+				testLinks[Link{From: *from, To: *to, Text: fmt.Sprint(i)}] = &LinkInfo{Seq: i + 1}
+			}
+			indexed, err := SummaryLink(testLinks)
+			if err != nil {
+				return false
+			}
+			pairwise, err := SummaryLinkBy(testLinks, URLSimilarityFunc(isSimilerURL))
+			if err != nil {
+				return false
+			}
+			baseline := uniqURLBaseline(testLinks, URLSimilarityFunc(isSimilerURL))
+			return reflect.DeepEqual(indexed, pairwise) &&
+				reflect.DeepEqual(baseline, UniqURL(testLinks)) &&
+				reflect.DeepEqual(baseline, UniqURLBy(testLinks, URLSimilarityFunc(isSimilerURL)))
+		},
+		gen.SliceOf(gen.UInt8()),
+	))
+
 	properties.TestingRun(t)
 }
 
+func benchLinks(n int) Links {
+	bLinks := make(Links)
+	for i := 0; i < n; i++ {
+		from, _ := url.Parse(fmt.Sprintf("http://example.com/page%d?id=%d", i%100, i))
+		to, _ := url.Parse(fmt.Sprintf("http://example.com/page%d?id=%d&tab=%d", i%1000, i, i%7))
+		bLinks[Link{From: *from, To: *to}] = &LinkInfo{Seq: i + 1}
+	}
+	return bLinks
+}
+
+func BenchmarkSummaryLink(b *testing.B) {
+	bLinks := benchLinks(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SummaryLink(bLinks)
+	}
+}
+
+func BenchmarkUniqURL(b *testing.B) {
+	bLinks := benchLinks(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		UniqURL(bLinks)
+	}
+}
+
 var uRLSymbolT = rangetable.New('-', '.', '_', '~')
 var digitT = rangetable.New('0', '1', '2', '3', '4', '5', '6', '7', '8', '9')
 var lowerT = rangetable.New('a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z')
 var upperT = rangetable.New('A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O', 'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z')
 var uRLRT = rangetable.Merge(uRLSymbolT, digitT, lowerT, upperT)
+
+func TestScrapeContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			// interrupted while fetching the entry
+			cancel()
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a>`)
+		case "/a":
+			fmt.Fprint(w, `<a href="/c">c</a>`)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "goscraper")
+	if err != nil {
+		t.Fatalf("failed to create temp dir:%v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.jsonl")
+	state, err := OpenCrawlState(filename, false)
+	if err != nil {
+		t.Fatalf("error in OpenCrawlState:%v", err)
+	}
+
+	sink := &recordSink{}
+	lsc, err := NewLinkScraper(&Config{
+		Logger: logger,
+		Entry:  ts.URL + "/",
+		Sink:   sink,
+		State:  state,
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if err := lsc.ScrapeContext(ctx); err != context.Canceled {
+		t.Errorf("not canceled:%v", err)
+	}
+	state.Close()
+	if expect := "[/a 0 /b 0]"; fmt.Sprint(sink.records) != expect || !sink.closed {
+		t.Errorf("not matched partial output,\nwant: %v,\nhave: %v", expect, sink.records)
+	}
+
+	state, err = OpenCrawlState(filename, true)
+	if err != nil {
+		t.Fatalf("error in OpenCrawlState:%v", err)
+	}
+	defer state.Close()
+	if pending := state.Pending(); len(pending) != 2 {
+		t.Errorf("not left links to resume: %v", pending)
+	}
+}
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"github.com/ynishi/goscraper"
+)
+
+const (
+	Damping    = 0.85
+	Iterations = 100
+)
+
+type Form struct {
+	From   string `json:"from"`
+	Action string `json:"action"`
+	Method string `json:"method"`
+}
+
+// Report is the result of all the analyses of the graph.
+type Report struct {
+	Entry            string             `json:"entry"`
+	Pages            int                `json:"pages"`
+	Links            int                `json:"links"`
+	Depths           map[string]int     `json:"depths"`
+	Unreachable      []string           `json:"unreachable"`
+	Orphans          []string           `json:"orphans"`
+	DeadEnds         []string           `json:"dead_ends"`
+	Degrees          map[string]Degree  `json:"degrees"`
+	PageRank         map[string]float64 `json:"pagerank"`
+	Components       [][]string         `json:"components"`
+	UnreachableForms []Form             `json:"unreachable_forms"`
+}
+
+func Analyze(links goscraper.Links, entry string) *Report {
+	g := New(links)
+	r := &Report{
+		Entry:            normalize(entry),
+		Pages:            len(g.Nodes),
+		Links:            len(links),
+		Depths:           g.Depths(entry),
+		Unreachable:      nonNil(g.Unreachable(entry)),
+		Orphans:          nonNil(g.Orphans(entry)),
+		DeadEnds:         nonNil(g.DeadEnds()),
+		Degrees:          g.Degrees(),
+		PageRank:         g.PageRank(Damping, Iterations),
+		Components:       append([][]string{}, g.Components()...),
+		UnreachableForms: []Form{},
+	}
+	for _, l := range g.UnreachableForms(entry) {
+		r.UnreachableForms = append(r.UnreachableForms, Form{From: l.From.String(), Action: l.To.String(), Method: l.Method})
+	}
+	return r
+}
+
+func nonNil(pages []string) []string {
+	if pages == nil {
+		return []string{}
+	}
+	return pages
+}
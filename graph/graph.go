@@ -0,0 +1,243 @@
+// Package graph analyzes the link graph of pages found by goscraper.
+package graph
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/ynishi/goscraper"
+)
+
+// Graph has a node per page URL without fragment, and an edge per pair of
+// pages with a link.
+type Graph struct {
+	Nodes  []string
+	Out    map[string][]string
+	In     map[string][]string
+	Status map[string]int
+	forms  []goscraper.Link
+}
+
+type Degree struct {
+	In  int `json:"in"`
+	Out int `json:"out"`
+}
+
+func nodeURL(u url.URL) string {
+	u.Fragment = ""
+	return u.String()
+}
+
+func New(links goscraper.Links) *Graph {
+	g := &Graph{
+		Out:    make(map[string][]string),
+		In:     make(map[string][]string),
+		Status: make(map[string]int),
+	}
+	nodes := make(map[string]bool)
+	edges := make(map[[2]string]bool)
+	for l, info := range links {
+		from, to := nodeURL(l.From), nodeURL(l.To)
+		nodes[from] = true
+		nodes[to] = true
+		if info != nil && info.StatusCode != 0 {
+			g.Status[to] = info.StatusCode
+		}
+		if l.Tag == "form" {
+			g.forms = append(g.forms, l)
+		}
+		if from == to || edges[[2]string{from, to}] {
+			continue
+		}
+		edges[[2]string{from, to}] = true
+		g.Out[from] = append(g.Out[from], to)
+		g.In[to] = append(g.In[to], from)
+	}
+	for n := range nodes {
+		g.Nodes = append(g.Nodes, n)
+		sort.Strings(g.Out[n])
+		sort.Strings(g.In[n])
+	}
+	sort.Strings(g.Nodes)
+	return g
+}
+
+func normalize(entry string) string {
+	u, err := url.Parse(entry)
+	if err != nil {
+		return entry
+	}
+	return nodeURL(*u)
+}
+
+// Depths returns the click depth from entry of each page reachable from it.
+func (g *Graph) Depths(entry string) map[string]int {
+	entry = normalize(entry)
+	depths := map[string]int{}
+	if i := sort.SearchStrings(g.Nodes, entry); i == len(g.Nodes) || g.Nodes[i] != entry {
+		return depths
+	}
+	depths[entry] = 0
+	queue := []string{entry}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, to := range g.Out[n] {
+			if _, ok := depths[to]; !ok {
+				depths[to] = depths[n] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+	return depths
+}
+
+// Unreachable returns the pages which can not be reached from entry.
+func (g *Graph) Unreachable(entry string) (pages []string) {
+	depths := g.Depths(entry)
+	for _, n := range g.Nodes {
+		if _, ok := depths[n]; !ok {
+			pages = append(pages, n)
+		}
+	}
+	return pages
+}
+
+// Orphans returns the pages without inbound links, except entry.
+func (g *Graph) Orphans(entry string) (pages []string) {
+	entry = normalize(entry)
+	for _, n := range g.Nodes {
+		if n != entry && len(g.In[n]) == 0 {
+			pages = append(pages, n)
+		}
+	}
+	return pages
+}
+
+// DeadEnds returns the pages fetched successfully without outbound links.
+// Pages which were not fetched are not dead ends, as their links are unknown.
+func (g *Graph) DeadEnds() (pages []string) {
+	for _, n := range g.Nodes {
+		status := g.Status[n]
+		if len(g.Out[n]) == 0 && status >= http.StatusOK && status < http.StatusMultipleChoices {
+			pages = append(pages, n)
+		}
+	}
+	return pages
+}
+
+func (g *Graph) Degrees() map[string]Degree {
+	degrees := make(map[string]Degree)
+	for _, n := range g.Nodes {
+		degrees[n] = Degree{In: len(g.In[n]), Out: len(g.Out[n])}
+	}
+	return degrees
+}
+
+// PageRank returns the PageRank of the pages, which sum to 1. Rank of pages
+// without outbound links is spread over all pages.
+func (g *Graph) PageRank(damping float64, iterations int) map[string]float64 {
+	n := float64(len(g.Nodes))
+	rank := make(map[string]float64)
+	for _, node := range g.Nodes {
+		rank[node] = 1 / n
+	}
+	for i := 0; i < iterations; i++ {
+		dangling := 0.0
+		for _, node := range g.Nodes {
+			if len(g.Out[node]) == 0 {
+				dangling += rank[node]
+			}
+		}
+		next := make(map[string]float64)
+		diff := 0.0
+		for _, node := range g.Nodes {
+			sum := 0.0
+			for _, from := range g.In[node] {
+				sum += rank[from] / float64(len(g.Out[from]))
+			}
+			next[node] = (1-damping)/n + damping*(sum+dangling/n)
+			diff += math.Abs(next[node] - rank[node])
+		}
+		rank = next
+		if diff < 1e-9 {
+			break
+		}
+	}
+	return rank
+}
+
+// Components returns the strongly connected components with more than one
+// page, largest first.
+func (g *Graph) Components() (components [][]string) {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := []string{}
+	var strongconnect func(v string)
+	strongconnect = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.Out[v] {
+			if _, ok := index[w]; !ok {
+				strongconnect(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		component := []string{}
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+	for _, n := range g.Nodes {
+		if _, ok := index[n]; !ok {
+			strongconnect(n)
+		}
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		if len(components[i]) != len(components[j]) {
+			return len(components[i]) > len(components[j])
+		}
+		return components[i][0] < components[j][0]
+	})
+	return components
+}
+
+// UnreachableForms returns the forms on pages which can not be reached from
+// entry.
+func (g *Graph) UnreachableForms(entry string) (forms []goscraper.Link) {
+	depths := g.Depths(entry)
+	for _, l := range g.forms {
+		if _, ok := depths[nodeURL(l.From)]; !ok {
+			forms = append(forms, l)
+		}
+	}
+	sort.Slice(forms, func(i, j int) bool {
+		if forms[i].From.String() != forms[j].From.String() {
+			return forms[i].From.String() < forms[j].From.String()
+		}
+		return forms[i].To.String() < forms[j].To.String()
+	})
+	return forms
+}
@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"math"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/ynishi/goscraper"
+)
+
+func testLinks() goscraper.Links {
+	newLink := func(from, to, tag string, status int) (goscraper.Link, *goscraper.LinkInfo) {
+		f, _ := url.Parse("http://example.com" + from)
+		t, _ := url.Parse("http://example.com" + to)
+		return goscraper.Link{From: *f, To: *t, Tag: tag, Method: "GET"}, &goscraper.LinkInfo{StatusCode: status}
+	}
+	links := goscraper.Links{}
+	for _, l := range [][]string{
+		{"/", "/a", "a"},
+		{"/", "/b#top", "a"},
+		{"/a", "/b", "a"},
+		{"/b", "/a", "a"},
+		{"/b", "/c", "a"},
+		{"/x", "/y", "a"},
+		{"/x", "/post", "form"},
+	} {
+		status := 200
+		if l[1] == "/y" {
+			status = 0
+		}
+		link, info := newLink(l[0], l[1], l[2], status)
+		links[link] = info
+	}
+	return links
+}
+
+func TestGraph(t *testing.T) {
+	g := New(testLinks())
+	entry := "http://example.com/"
+	u := func(path string) string { return "http://example.com" + path }
+
+	expectDepths := map[string]int{u("/"): 0, u("/a"): 1, u("/b"): 1, u("/c"): 2}
+	if depths := g.Depths(entry); !reflect.DeepEqual(expectDepths, depths) {
+		t.Errorf("not matched depths,\nwant: %v,\nhave: %v", expectDepths, depths)
+	}
+	if depths := g.Depths("http://example.com/none"); len(depths) != 0 {
+		t.Errorf("depths from unknown entry: %v", depths)
+	}
+	if expect, have := []string{u("/post"), u("/x"), u("/y")}, g.Unreachable(entry); !reflect.DeepEqual(expect, have) {
+		t.Errorf("not matched unreachable,\nwant: %v,\nhave: %v", expect, have)
+	}
+	if expect, have := []string{u("/x")}, g.Orphans(entry); !reflect.DeepEqual(expect, have) {
+		t.Errorf("not matched orphans,\nwant: %v,\nhave: %v", expect, have)
+	}
+	if expect, have := []string{u("/c"), u("/post")}, g.DeadEnds(); !reflect.DeepEqual(expect, have) {
+		t.Errorf("not matched dead ends,\nwant: %v,\nhave: %v", expect, have)
+	}
+	if expect, have := (Degree{In: 2, Out: 2}), g.Degrees()[u("/b")]; expect != have {
+		t.Errorf("not matched degree,\nwant: %v,\nhave: %v", expect, have)
+	}
+	if expect, have := [][]string{{u("/a"), u("/b")}}, g.Components(); !reflect.DeepEqual(expect, have) {
+		t.Errorf("not matched components,\nwant: %v,\nhave: %v", expect, have)
+	}
+	forms := g.UnreachableForms(entry)
+	if len(forms) != 1 || forms[0].To.Path != "/post" {
+		t.Errorf("not matched unreachable forms: %v", forms)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	rank := New(testLinks()).PageRank(Damping, Iterations)
+	sum := 0.0
+	for _, r := range rank {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("not matched sum of ranks: %v", sum)
+	}
+	if !(rank["http://example.com/b"] > rank["http://example.com/"] && rank["http://example.com/a"] > rank["http://example.com/x"]) {
+		t.Errorf("not ranked: %v", rank)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	r := Analyze(testLinks(), "http://example.com/#main")
+	if r.Entry != "http://example.com/" || r.Pages != 7 || r.Links != 7 || len(r.UnreachableForms) != 1 {
+		t.Errorf("not matched report: %+v", r)
+	}
+}
@@ -0,0 +1,161 @@
+package goscraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// LinkSchemaVersion is the version of the link json, see
+// schema/links.schema.json. Links without version have url.URL objects as
+// from and to, which are still read.
+const LinkSchemaVersion = 1
+
+type linkJSON struct {
+	SchemaVersion int             `json:"schema_version"`
+	From          json.RawMessage `json:"from"`
+	To            json.RawMessage `json:"to"`
+	AttrId        string          `json:"attr_id"`
+	AttrOnClick   string          `json:"attr_onclick"`
+	Text          string          `json:"text"`
+	Tag           string          `json:"tag"`
+	Method        string          `json:"method"`
+	Selector      string          `json:"selector"`
+}
+
+func (l Link) MarshalJSON() ([]byte, error) {
+	from, _ := json.Marshal(l.From.String())
+	to, _ := json.Marshal(l.To.String())
+	return json.Marshal(&linkJSON{
+		SchemaVersion: LinkSchemaVersion,
+		From:          from,
+		To:            to,
+		AttrId:        l.AttrId,
+		AttrOnClick:   l.AttrOnClick,
+		Text:          l.Text,
+		Tag:           l.Tag,
+		Method:        l.Method,
+		Selector:      l.Selector,
+	})
+}
+
+func (l *Link) UnmarshalJSON(b []byte) error {
+	v := &linkJSON{}
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+	if v.SchemaVersion > LinkSchemaVersion {
+		return fmt.Errorf("not supported schema version:%d", v.SchemaVersion)
+	}
+	from, err := unmarshalURL(v.From)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal from:%v", err)
+	}
+	to, err := unmarshalURL(v.To)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal to:%v", err)
+	}
+	*l = Link{
+		From:        *from,
+		To:          *to,
+		AttrId:      v.AttrId,
+		AttrOnClick: v.AttrOnClick,
+		Text:        v.Text,
+		Tag:         v.Tag,
+		Method:      v.Method,
+		Selector:    v.Selector,
+	}
+	return nil
+}
+
+// unmarshalURL reads a url string, or a url.URL object of the old format.
+func unmarshalURL(b json.RawMessage) (*url.URL, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		return &url.URL{}, nil
+	}
+	if b[0] != '"' {
+		u := &url.URL{}
+		err := json.Unmarshal(b, u)
+		return u, err
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return url.Parse(s)
+}
+
+var linkTextFields = []string{"from", "to", "attr_id", "attr_onclick", "text", "tag", "method", "selector"}
+
+// MarshalText encodes the link as a query string, e.g. for keys of Links in
+// json.
+func (l Link) MarshalText() ([]byte, error) {
+	values := url.Values{}
+	for i, v := range []string{l.From.String(), l.To.String(), l.AttrId, l.AttrOnClick, l.Text, l.Tag, l.Method, l.Selector} {
+		if v != "" {
+			values.Set(linkTextFields[i], v)
+		}
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (l *Link) UnmarshalText(b []byte) error {
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return fmt.Errorf("failed to parse link:%v", err)
+	}
+	from, err := url.Parse(values.Get("from"))
+	if err != nil {
+		return fmt.Errorf("failed to parse from:%v", err)
+	}
+	to, err := url.Parse(values.Get("to"))
+	if err != nil {
+		return fmt.Errorf("failed to parse to:%v", err)
+	}
+	*l = Link{
+		From:        *from,
+		To:          *to,
+		AttrId:      values.Get("attr_id"),
+		AttrOnClick: values.Get("attr_onclick"),
+		Text:        values.Get("text"),
+		Tag:         values.Get("tag"),
+		Method:      values.Get("method"),
+		Selector:    values.Get("selector"),
+	}
+	return nil
+}
+
+// MarshalJSON writes the fields of Link and LinkInfo in an object, as the
+// promoted Link.MarshalJSON would drop LinkInfo.
+func (r LinkRecord) MarshalJSON() ([]byte, error) {
+	b, err := r.Link.MarshalJSON()
+	if err != nil || r.LinkInfo == nil {
+		return b, err
+	}
+	info, err := json.Marshal(r.LinkInfo)
+	if err != nil {
+		return nil, err
+	}
+	if len(info) <= 2 {
+		return b, nil
+	}
+	return append(append(b[:len(b)-1], ','), info[1:]...), nil
+}
+
+// UnmarshalJSON leaves LinkInfo nil if no field of it is set.
+func (r *LinkRecord) UnmarshalJSON(b []byte) error {
+	if err := r.Link.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	info := &LinkInfo{}
+	if err := json.Unmarshal(b, info); err != nil {
+		return err
+	}
+	r.LinkInfo = nil
+	if *info != (LinkInfo{}) {
+		r.LinkInfo = info
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package goscraper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkJSON(t *testing.T) {
+	l := *ls[0]
+	l.AttrId, l.Text, l.Tag, l.Method = "id1", "a & b", "a", "GET"
+	b, err := json.Marshal(l)
+	if err != nil {
+		t.Fatalf("error in Marshal:%v", err)
+	}
+	expect := `{"schema_version":1,"from":"http://example.com","to":"http://example.com?a1=v11\u0026a2=v2","attr_id":"id1","attr_onclick":"","text":"a \u0026 b","tag":"a","method":"GET","selector":""}`
+	if string(b) != expect {
+		t.Errorf("not matched json,\nwant: %s,\nhave: %s", expect, b)
+	}
+	read := Link{}
+	if err := json.Unmarshal(b, &read); err != nil || read != l {
+		t.Errorf("not matched link,\nwant: %v,\nhave: %v, %v", l, read, err)
+	}
+
+	old := `{"from":{"Scheme":"http","Opaque":"","User":null,"Host":"example.com","Path":"","RawPath":"","ForceQuery":false,"RawQuery":"","Fragment":""},` +
+		`"to":{"Scheme":"http","Opaque":"","User":null,"Host":"example.com","Path":"","RawPath":"","ForceQuery":false,"RawQuery":"a1=v11&a2=v2","Fragment":""},"tag":"a"}`
+	read = Link{}
+	if err := json.Unmarshal([]byte(old), &read); err != nil || read.From != ls[0].From || read.To != ls[0].To || read.Tag != "a" {
+		t.Errorf("not matched old link: %v, %v", read, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"schema_version":2}`), &read); err == nil {
+		t.Errorf("no error in unknown schema version")
+	}
+}
+
+func TestLinkRecordJSON(t *testing.T) {
+	seen := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	for _, rec := range []LinkRecord{
+		{Link: *ls[1], LinkInfo: &LinkInfo{Seq: 1, StatusCode: 200, FirstSeen: seen, LastSeen: seen, Form: &Form{Action: "/save"}}},
+		{Link: *ls[1]},
+	} {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			t.Fatalf("error in Marshal:%v", err)
+		}
+		read := LinkRecord{}
+		if err := json.Unmarshal(b, &read); err != nil || !reflect.DeepEqual(rec, read) {
+			t.Errorf("not matched record,\nwant: %v,\nhave: %v, %v", rec, read, err)
+		}
+	}
+}
+
+func TestLinkText(t *testing.T) {
+	testLinks := Links{
+		*ls[0]: {Seq: 1},
+		*ls[3]: {Seq: 2},
+	}
+	b, err := json.Marshal(testLinks)
+	if err != nil {
+		t.Fatalf("error in Marshal:%v", err)
+	}
+	read := Links{}
+	if err := json.Unmarshal(b, &read); err != nil || !reflect.DeepEqual(testLinks, read) {
+		t.Errorf("not matched links,\nwant: %v,\nhave: %v, %v", testLinks, read, err)
+	}
+}
+
+func TestLinkSchema(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("schema", "links.schema.json"))
+	if err != nil {
+		t.Fatalf("error in ReadFile:%v", err)
+	}
+	schema := struct {
+		Definitions map[string]struct {
+			Required   []string               `json:"required"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"definitions"`
+	}{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("invalid schema:%v", err)
+	}
+	link := schema.Definitions["link"]
+
+	rec := LinkRecord{Link: *ls[0], LinkInfo: &LinkInfo{
+		Seq: 1, Depth: 1, StatusCode: 404, ContentType: "text/html", Error: "Not Found",
+		FirstSeen: time.Now(), LastSeen: time.Now(), SeenCount: 1, ResponseTime: time.Second,
+		Form: &Form{}, Skipped: "deny:logout-url", Attempts: 2, Outcome: OutcomeTRANSIENT, ErrorClass: ClassSERVER,
+	}}
+	b, _ = json.Marshal(rec)
+	fields := map[string]interface{}{}
+	json.Unmarshal(b, &fields)
+	for k := range fields {
+		if _, ok := link.Properties[k]; !ok {
+			t.Errorf("not in schema: %s", k)
+		}
+	}
+	for _, k := range link.Required {
+		if _, ok := fields[k]; !ok {
+			t.Errorf("not in json: %s", k)
+		}
+	}
+	if !strings.Contains(string(b), `"schema_version":1`) {
+		t.Errorf("no schema version: %s", b)
+	}
+}
@@ -0,0 +1,263 @@
+package goscraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type SegmentKind string
+
+const (
+	SegmentLiteral SegmentKind = ""
+	SegmentID      SegmentKind = "id"
+	SegmentUUID    SegmentKind = "uuid"
+	SegmentHash    SegmentKind = "hash"
+	SegmentDate    SegmentKind = "date"
+	SegmentValue   SegmentKind = "value"
+)
+
+var MaxPatternExamples = 5
+
+var (
+	reSegmentDate = regexp.MustCompile(`^\d{4}-\d{1,2}-\d{1,2}([T ]\d{1,2}:\d{2}(:\d{2})?.*)?$`)
+	reSegmentID   = regexp.MustCompile(`^\d+$`)
+	reSegmentUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	reSegmentHash = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+)
+
+// ClassifySegment returns the kind of variable a path segment or query value
+// looks like, or SegmentLiteral if it looks like a fixed word.
+func ClassifySegment(v string) SegmentKind {
+	switch {
+	case reSegmentDate.MatchString(v):
+		return SegmentDate
+	case reSegmentID.MatchString(v):
+		return SegmentID
+	case reSegmentUUID.MatchString(v):
+		return SegmentUUID
+	case reSegmentHash.MatchString(v):
+		return SegmentHash
+	default:
+		return SegmentLiteral
+	}
+}
+
+type Segment struct {
+	Value    string      `json:"value,omitempty"`
+	Kind     SegmentKind `json:"kind,omitempty"`
+	Examples []string    `json:"examples,omitempty"`
+}
+
+func newSegment(v string) *Segment {
+	kind := ClassifySegment(v)
+	if kind == SegmentLiteral {
+		return &Segment{Value: v}
+	}
+	return &Segment{Kind: kind, Examples: []string{v}}
+}
+
+func (s *Segment) IsVar() bool {
+	return s.Kind != SegmentLiteral
+}
+
+func (s *Segment) String() string {
+	if s.IsVar() {
+		return fmt.Sprintf("{%s}", s.Kind)
+	}
+	return s.Value
+}
+
+func (s *Segment) addExample(v string) {
+	for _, e := range s.Examples {
+		if e == v {
+			return
+		}
+	}
+	if len(s.Examples) < MaxPatternExamples {
+		s.Examples = append(s.Examples, v)
+	}
+}
+
+func (s *Segment) acceptsPath(o *Segment) bool {
+	return s.Kind == o.Kind && (s.IsVar() || s.Value == o.Value)
+}
+
+func (s *Segment) mergePath(o *Segment) {
+	for _, e := range o.Examples {
+		s.addExample(e)
+	}
+}
+
+// query values always merge: differing values turn the segment into a
+// variable, keeping the more specific kind when both sides agree on it.
+func (s *Segment) mergeQuery(o *Segment) {
+	switch {
+	case !s.IsVar() && !o.IsVar() && s.Value == o.Value:
+		return
+	case s.Kind != o.Kind || !s.IsVar():
+		if !s.IsVar() {
+			s.Examples = []string{s.Value}
+		}
+		s.Kind = SegmentValue
+		s.Value = ""
+	}
+	if o.IsVar() {
+		for _, e := range o.Examples {
+			s.addExample(e)
+		}
+	} else {
+		s.addExample(o.Value)
+	}
+}
+
+// URLPattern is a URL template such as http://example.com/users/{id}/edit,
+// built from one or more concrete URLs. Query has a segment per value of a
+// key, in the order of the URL.
+type URLPattern struct {
+	Scheme string                `json:"scheme"`
+	Host   string                `json:"host"`
+	Path   []*Segment            `json:"path"`
+	Query  map[string][]*Segment `json:"query,omitempty"`
+}
+
+func NewURLPattern(u *url.URL) *URLPattern {
+	p := &URLPattern{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Query:  make(map[string][]*Segment),
+	}
+	for _, v := range strings.Split(u.Path, "/") {
+		p.Path = append(p.Path, newSegment(v))
+	}
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			p.Query[k] = append(p.Query[k], newSegment(v))
+		}
+	}
+	return p
+}
+
+func (p *URLPattern) compatible(o *URLPattern) bool {
+	if p.Scheme != o.Scheme || p.Host != o.Host || len(p.Path) != len(o.Path) || len(p.Query) != len(o.Query) {
+		return false
+	}
+	for i, s := range p.Path {
+		if !s.acceptsPath(o.Path[i]) {
+			return false
+		}
+	}
+	for k, vs := range o.Query {
+		if len(p.Query[k]) != len(vs) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *URLPattern) Match(u *url.URL) bool {
+	return p.compatible(NewURLPattern(u))
+}
+
+// Merge widens the pattern with the values of u. It fails if u does not have
+// the same scheme and host, the same fixed path segments and the same number
+// of values of each query key.
+func (p *URLPattern) Merge(u *url.URL) error {
+	o := NewURLPattern(u)
+	if !p.compatible(o) {
+		return fmt.Errorf("not matched pattern:%s:%s", p, u)
+	}
+	for i, s := range p.Path {
+		s.mergePath(o.Path[i])
+	}
+	for k, ss := range p.Query {
+		for i, s := range ss {
+			s.mergeQuery(o.Query[k][i])
+		}
+	}
+	return nil
+}
+
+func (p *URLPattern) Vars() (segments []*Segment) {
+	for _, s := range p.Path {
+		if s.IsVar() {
+			segments = append(segments, s)
+		}
+	}
+	for _, k := range p.queryKeys() {
+		for _, s := range p.Query[k] {
+			if s.IsVar() {
+				segments = append(segments, s)
+			}
+		}
+	}
+	return segments
+}
+
+func (p *URLPattern) queryKeys() []string {
+	keys := make([]string, 0, len(p.Query))
+	for k := range p.Query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (p *URLPattern) path() string {
+	ss := make([]string, len(p.Path))
+	for i, s := range p.Path {
+		ss[i] = s.String()
+	}
+	return strings.Join(ss, "/")
+}
+
+// Key identifies the group of URLs the pattern matches, so patterns with the
+// same key can be merged. A query key is repeated per value.
+func (p *URLPattern) Key() string {
+	qs := []string{}
+	for _, k := range p.queryKeys() {
+		for range p.Query[k] {
+			qs = append(qs, k)
+		}
+	}
+	return fmt.Sprintf("%s://%s%s?%s", p.Scheme, p.Host, p.path(), strings.Join(qs, "&"))
+}
+
+func (p *URLPattern) String() string {
+	s := fmt.Sprintf("%s://%s%s", p.Scheme, p.Host, p.path())
+	if len(p.Query) == 0 {
+		return s
+	}
+	qs := []string{}
+	for _, k := range p.queryKeys() {
+		for _, s := range p.Query[k] {
+			qs = append(qs, fmt.Sprintf("%s=%s", k, s))
+		}
+	}
+	return fmt.Sprintf("%s?%s", s, strings.Join(qs, "&"))
+}
+
+func UniqURLPattern(links Links) (patterns []*URLPattern) {
+	index := make(map[string]*URLPattern)
+	add := func(u url.URL) {
+		p := NewURLPattern(&u)
+		if found, ok := index[p.Key()]; ok {
+			found.Merge(&u)
+			return
+		}
+		index[p.Key()] = p
+		patterns = append(patterns, p)
+	}
+	// in discovery order, for the examples to be the first URLs found
+	sorted, _ := SortLinks(links, OrderDISCOVERY)
+	for _, l := range sorted {
+		add(l.From)
+		add(l.To)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].String() < patterns[j].String()
+	})
+	return patterns
+}
@@ -0,0 +1,95 @@
+package goscraper
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClassifySegment(t *testing.T) {
+	tests := map[string]SegmentKind{
+		"users":                                SegmentLiteral,
+		"":                                     SegmentLiteral,
+		"123":                                  SegmentID,
+		"2018-06-01":                           SegmentDate,
+		"2018-06-01T10:00:00Z":                 SegmentDate,
+		"0b7c6f3e-8f5d-4a52-9b1e-2d1b7e1f0a11": SegmentUUID,
+		"d41d8cd98f00b204e9800998ecf8427e":     SegmentHash,
+		"v11":                                  SegmentLiteral,
+	}
+	for v, expect := range tests {
+		if kind := ClassifySegment(v); kind != expect {
+			t.Errorf("not matched: %s,\nwant: %v,\nhave: %v", v, expect, kind)
+		}
+	}
+}
+
+func TestURLPattern(t *testing.T) {
+	u1, _ := url.Parse("http://example.com/users/123/edit?tab=profile&since=2018-01-01")
+	u2, _ := url.Parse("http://example.com/users/456/edit?tab=posts&since=2018-02-01")
+	u3, _ := url.Parse("http://example.com/users/456/show?tab=posts&since=2018-02-01")
+
+	p := NewURLPattern(u1)
+	if err := p.Merge(u2); err != nil {
+		t.Errorf("error in Merge:%v", err)
+	}
+	if err := p.Merge(u3); err == nil {
+		t.Errorf("merged not matched url: %v", u3)
+	}
+	expect := "http://example.com/users/{id}/edit?since={date}&tab={value}"
+	if p.String() != expect {
+		t.Errorf("not matched,\nwant: %v,\nhave: %v", expect, p.String())
+	}
+	if !p.Match(u1) || !p.Match(u2) || p.Match(u3) {
+		t.Errorf("not matched pattern: %v", p)
+	}
+	examples := [][]string{{"123", "456"}, {"2018-01-01", "2018-02-01"}, {"profile", "posts"}}
+	for i, s := range p.Vars() {
+		if !reflect.DeepEqual(examples[i], s.Examples) {
+			t.Errorf("not matched,\nwant: %v,\nhave: %v", examples[i], s.Examples)
+		}
+	}
+}
+
+func TestUniqURLPattern(t *testing.T) {
+	from, _ := url.Parse("http://example.com/users")
+	link := func(to string) Link {
+		u, _ := url.Parse(to)
+		return Link{From: *from, To: *u}
+	}
+	testLinks := Links{
+		link("http://example.com/users/3"):             {Seq: 1},
+		link("http://example.com/users/1"):             {Seq: 2},
+		link("http://example.com/users/2"):             {Seq: 3},
+		link("https://example.com/users/4"):            {Seq: 4},
+		link("http://example.com/search?tag=a&tag=b"):  {Seq: 5},
+		link("http://example.com/search?tag=c&tag=12"): {Seq: 6},
+		link("http://example.com/search?tag=d"):        {Seq: 7},
+	}
+	patterns := UniqURLPattern(testLinks)
+	expect := []string{
+		"http://example.com/search?tag=d",
+		"http://example.com/search?tag={value}&tag={value}",
+		"http://example.com/users",
+		"http://example.com/users/{id}",
+		"https://example.com/users/{id}",
+	}
+	if len(patterns) != len(expect) {
+		t.Fatalf("not matched,\nwant: %v,\nhave: %v", expect, patterns)
+	}
+	for i, p := range patterns {
+		if p.String() != expect[i] {
+			t.Errorf("not matched,\nwant: %v,\nhave: %v", expect[i], p)
+		}
+	}
+	// examples in discovery order
+	examples := [][]string{{"a", "c"}, {"b", "12"}}
+	for i, s := range patterns[1].Vars() {
+		if !reflect.DeepEqual(examples[i], s.Examples) {
+			t.Errorf("not matched,\nwant: %v,\nhave: %v", examples[i], s.Examples)
+		}
+	}
+	if ids := patterns[3].Vars()[0].Examples; !reflect.DeepEqual([]string{"3", "1", "2"}, ids) {
+		t.Errorf("not matched examples: %v", ids)
+	}
+}
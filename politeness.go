@@ -0,0 +1,235 @@
+package goscraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/temoto/robotstxt"
+)
+
+const (
+	SkippedROBOTS = "robots.txt"
+	SkippedBUDGET = "budget"
+)
+
+// Politeness throttles the requests of a crawl per host. Zero values turn
+// each part off, and a nil Politeness does nothing.
+type Politeness struct {
+	// Rate is the requests per second to a host, Burst the requests allowed
+	// at once, 1 by default.
+	Rate  float64
+	Burst int
+	// Robots denies links disallowed by robots.txt for UserAgent, and waits
+	// its Crawl-delay between requests to a host.
+	Robots    bool
+	UserAgent string
+	// Backoff is the wait after 429 or 503 without Retry-After, MaxBackoff
+	// caps Retry-After, and MaxBackoffRetries is the retries of a request.
+	Backoff           time.Duration
+	MaxBackoff        time.Duration
+	MaxBackoffRetries int
+	// Budget is the max requests of a crawl. Requests already queued when it
+	// is spent are still made.
+	Budget int
+	Client *http.Client
+
+	mu       sync.Mutex
+	hosts    map[string]*politeHost
+	requests int
+	retries  map[string]int
+}
+
+type politeHost struct {
+	tokens float64
+	filled time.Time
+	next   time.Time // by Crawl-delay
+	until  time.Time // by Retry-After
+	once   sync.Once
+	robots *robotstxt.Group
+}
+
+func (p *Politeness) host(u *url.URL) *politeHost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.hosts == nil {
+		p.hosts = make(map[string]*politeHost)
+	}
+	h, ok := p.hosts[u.Host]
+	if !ok {
+		h = &politeHost{tokens: float64(p.burst()), filled: time.Now()}
+		p.hosts[u.Host] = h
+	}
+	return h
+}
+
+func (p *Politeness) burst() int {
+	if p.Burst < 1 {
+		return 1
+	}
+	return p.Burst
+}
+
+// robotsGroup fetches robots.txt of the host of u once. No robots.txt or a
+// failed fetch allows all.
+func (p *Politeness) robotsGroup(u *url.URL) *robotstxt.Group {
+	h := p.host(u)
+	h.once.Do(func() {
+		h.robots, _ = p.fetchRobots(u)
+	})
+	return h.robots
+}
+
+func (p *Politeness) fetchRobots(u *url.URL) (*robotstxt.Group, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequest(http.MethodGet, u.Scheme+"://"+u.Host+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create robots.txt request:%v", err)
+	}
+	if p.UserAgent != "" {
+		req.Header.Set("User-Agent", p.UserAgent)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get robots.txt:%v", err)
+	}
+	defer res.Body.Close()
+	robots, err := robotstxt.FromResponse(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse robots.txt:%v", err)
+	}
+	return robots.FindGroup(p.UserAgent), nil
+}
+
+// Deny returns the reason not to request u, robots.txt if disallowed or
+// budget if spent.
+func (p *Politeness) Deny(u *url.URL) (reason string, ok bool) {
+	if p == nil {
+		return "", false
+	}
+	if p.Budget > 0 && p.Requests() >= p.Budget {
+		return SkippedBUDGET, true
+	}
+	if p.Robots {
+		if group := p.robotsGroup(u); group != nil && !group.Test(u.RequestURI()) {
+			return SkippedROBOTS, true
+		}
+	}
+	return "", false
+}
+
+// Wait blocks until a request to u is allowed by Rate, Crawl-delay and
+// backoff, and counts it in Budget.
+func (p *Politeness) Wait(ctx context.Context, u *url.URL) error {
+	if p == nil {
+		return nil
+	}
+	var group *robotstxt.Group
+	if p.Robots {
+		group = p.robotsGroup(u)
+	}
+	h := p.host(u)
+
+	p.mu.Lock()
+	p.requests++
+	now := time.Now()
+	at := now
+	if h.until.After(at) {
+		at = h.until
+	}
+	if p.Rate > 0 {
+		// a token bucket, where a negative balance reserves future tokens
+		h.tokens += now.Sub(h.filled).Seconds() * p.Rate
+		if max := float64(p.burst()); h.tokens > max {
+			h.tokens = max
+		}
+		h.filled = now
+		h.tokens--
+		if h.tokens < 0 {
+			if t := now.Add(time.Duration(-h.tokens / p.Rate * float64(time.Second))); t.After(at) {
+				at = t
+			}
+		}
+	}
+	if group != nil && group.CrawlDelay > 0 {
+		if h.next.After(at) {
+			at = h.next
+		}
+		h.next = at.Add(group.CrawlDelay)
+	}
+	p.mu.Unlock()
+
+	return sleepContext(ctx, at.Sub(now))
+}
+
+// BackOff pauses the host of r after 429 or 503 until Retry-After, and
+// returns the wait if r.Request should be retried.
+func (p *Politeness) BackOff(r *colly.Response) (wait time.Duration, retry bool) {
+	if p == nil || (r.StatusCode != http.StatusTooManyRequests && r.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	wait = p.Backoff
+	if r.Headers != nil {
+		if d, ok := retryAfter(r.Headers.Get("Retry-After"), time.Now()); ok {
+			wait = d
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	h := p.host(r.Request.URL)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(h.until) {
+		h.until = until
+	}
+	if p.retries == nil {
+		p.retries = make(map[string]int)
+	}
+	key := fmt.Sprintf("%s %s", r.Request.Method, r.Request.URL.String())
+	if p.retries[key] >= p.MaxBackoffRetries {
+		return wait, false
+	}
+	p.retries[key]++
+	return wait, true
+}
+
+// Requests returns the requests counted in Budget.
+func (p *Politeness) Requests() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+// retryAfter parses Retry-After of seconds or an http date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		if sec < 0 {
+			return 0, false
+		}
+		return time.Duration(sec) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
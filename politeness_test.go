@@ -0,0 +1,137 @@
+package goscraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Fri, 01 Jun 2018 10:00:30 GMT", 30 * time.Second, true},
+		{"Fri, 01 Jun 2018 09:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	} {
+		wait, ok := retryAfter(tc.value, now)
+		if wait != tc.wait || ok != tc.ok {
+			t.Errorf("not matched retry after of %q: %v, %v", tc.value, wait, ok)
+		}
+	}
+}
+
+func TestPolitenessWait(t *testing.T) {
+	p := &Politeness{Rate: 20, Budget: 3}
+	a, _ := url.Parse("http://a.example.com/")
+	b, _ := url.Parse("http://b.example.com/")
+	start := time.Now()
+	p.Wait(context.Background(), a)
+	p.Wait(context.Background(), b)
+	if d := time.Since(start); d > 40*time.Millisecond {
+		t.Errorf("waited first requests: %v", d)
+	}
+	p.Wait(context.Background(), a)
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("not waited by rate: %v", d)
+	}
+	if reason, ok := p.Deny(a); !ok || reason != SkippedBUDGET || p.Requests() != 3 {
+		t.Errorf("not denied by budget: %v, %v", reason, p.Requests())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Wait(ctx, a); err != context.Canceled {
+		t.Errorf("not stopped by ctx: %v", err)
+	}
+
+	var nilp *Politeness
+	if _, ok := nilp.Deny(a); ok || nilp.Wait(nil, a) != nil {
+		t.Errorf("not ignored nil politeness")
+	}
+}
+
+func TestPoliteScrape(t *testing.T) {
+	var mu sync.Mutex
+	busy := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/busy">busy</a><a href="/private/a">private</a><a href="/p">p</a>`)
+		case "/busy":
+			mu.Lock()
+			defer mu.Unlock()
+			busy++
+			if busy == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		case "/private/a":
+			t.Errorf("requested disallowed url")
+		}
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	lsc, err := NewLinkScraper(&Config{
+		Logger:     logger,
+		Entry:      ts.URL + "/",
+		Sink:       &recordSink{},
+		Politeness: &Politeness{Robots: true, MaxBackoffRetries: 1},
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	lsc.Collector.AllowedDomains = []string{u.Host}
+	if err := lsc.Scrape(); err != nil {
+		t.Errorf("error in Scrape:%v", err)
+	}
+	for l, info := range lsc.Links {
+		switch l.To.Path {
+		case "/busy":
+			if info.StatusCode != http.StatusOK || busy != 2 {
+				t.Errorf("not retried after 429: %v, %d", info, busy)
+			}
+		case "/private/a":
+			if info.Skipped != SkippedROBOTS {
+				t.Errorf("not skipped by robots.txt: %v", info)
+			}
+		}
+	}
+
+	lsc, err = NewLinkScraper(&Config{
+		Logger:     logger,
+		Entry:      ts.URL + "/",
+		Sink:       &recordSink{},
+		Politeness: &Politeness{Budget: 2},
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if err := lsc.Scrape(); err != nil {
+		t.Errorf("error in Scrape:%v", err)
+	}
+	skipped := 0
+	for _, info := range lsc.Links {
+		if info.Skipped == SkippedBUDGET {
+			skipped++
+		}
+	}
+	if lsc.Politeness.Requests() != 2 || skipped != 2 {
+		t.Errorf("not spent budget: %d, %d", lsc.Politeness.Requests(), skipped)
+	}
+}
@@ -0,0 +1,219 @@
+package goscraper
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ReadLinksJSON reads links written by the json or jsonl output.
+func ReadLinksJSON(r io.Reader) (links Links, err error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	links = make(Links)
+	add := func(rec *LinkRecord) {
+		if rec.LinkInfo == nil {
+			rec.LinkInfo = &LinkInfo{Seq: len(links) + 1}
+		}
+		links[rec.Link] = rec.LinkInfo
+	}
+	if b, err := peekNonSpace(br); err == nil && b == '[' {
+		records := []*LinkRecord{}
+		if err := dec.Decode(&records); err != nil {
+			return nil, fmt.Errorf("failed to decode links:%v", err)
+		}
+		for _, rec := range records {
+			add(rec)
+		}
+		return links, nil
+	}
+	for {
+		rec := &LinkRecord{}
+		err := dec.Decode(rec)
+		if err == io.EOF {
+			return links, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode link:%d:%v", len(links)+1, err)
+		}
+		add(rec)
+	}
+}
+
+func ReadLinksJSONFile(filename string) (Links, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open links:%s:%v", filename, err)
+	}
+	defer f.Close()
+	return ReadLinksJSON(f)
+}
+
+// ReadLinksCSV reads links written by the csv output. Columns are found by
+// the header, so missing columns are left empty.
+func ReadLinksCSV(r io.Reader) (links Links, err error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return make(Links), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header:%v", err)
+	}
+	cols := make(map[string]int)
+	for i, name := range header {
+		cols[name] = i
+	}
+	links = make(Links)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return links, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv record:%v", err)
+		}
+		link, info, err := parseCsvRecord(cols, record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse csv record:%d:%v", len(links)+1, err)
+		}
+		if info.Seq == 0 {
+			info.Seq = len(links) + 1
+		}
+		links[link] = info
+	}
+}
+
+func parseCsvRecord(cols map[string]int, record []string) (link Link, info *LinkInfo, err error) {
+	get := func(name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	parseURL := func(name string) (url.URL, error) {
+		u, err := url.Parse(get(name))
+		if err != nil {
+			return url.URL{}, fmt.Errorf("failed to parse %s:%v", name, err)
+		}
+		return *u, nil
+	}
+	parseInt := func(name string) (int, error) {
+		if get(name) == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(get(name))
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %s:%v", name, err)
+		}
+		return n, nil
+	}
+	parseTime := func(name string) (time.Time, error) {
+		if get(name) == "" {
+			return time.Time{}, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, get(name))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse %s:%v", name, err)
+		}
+		return t, nil
+	}
+
+	link = Link{
+		AttrId:      get("attr_id"),
+		AttrOnClick: get("onclick"),
+		Text:        get("text"),
+		Tag:         get("tag"),
+		Method:      get("method"),
+		Selector:    get("selector"),
+	}
+	if link.From, err = parseURL("from"); err != nil {
+		return link, nil, err
+	}
+	if link.To, err = parseURL("to"); err != nil {
+		return link, nil, err
+	}
+	info = &LinkInfo{
+		ContentType: get("content_type"),
+		Error:       get("error"),
+		Skipped:     get("skipped_reason"),
+		Outcome:     get("outcome"),
+		ErrorClass:  get("error_class"),
+	}
+	if info.Seq, err = parseInt("seq"); err != nil {
+		return link, nil, err
+	}
+	if info.Depth, err = parseInt("depth"); err != nil {
+		return link, nil, err
+	}
+	if info.StatusCode, err = parseInt("status"); err != nil {
+		return link, nil, err
+	}
+	if info.SeenCount, err = parseInt("seen_count"); err != nil {
+		return link, nil, err
+	}
+	if info.Attempts, err = parseInt("attempts"); err != nil {
+		return link, nil, err
+	}
+	if info.FirstSeen, err = parseTime("first_seen"); err != nil {
+		return link, nil, err
+	}
+	if info.LastSeen, err = parseTime("last_seen"); err != nil {
+		return link, nil, err
+	}
+	if d := get("response_time"); d != "" {
+		if info.ResponseTime, err = time.ParseDuration(d); err != nil {
+			return link, nil, fmt.Errorf("failed to parse response_time:%v", err)
+		}
+	}
+	if f := get("form"); f != "" {
+		info.Form = &Form{}
+		if err := json.Unmarshal([]byte(f), info.Form); err != nil {
+			return link, nil, fmt.Errorf("failed to parse form:%v", err)
+		}
+	}
+	return link, info, nil
+}
+
+func ReadLinksCSVFile(filename string) (Links, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open links:%s:%v", filename, err)
+	}
+	defer f.Close()
+	return ReadLinksCSV(f)
+}
+
+// ReadLinksFile reads the csv, json or jsonl output by the extension of
+// filename.
+func ReadLinksFile(filename string) (Links, error) {
+	switch filepath.Ext(filename) {
+	case "." + OptOUTPUTCSV:
+		return ReadLinksCSVFile(filename)
+	case "." + OptOUTPUTJSON, "." + OptOUTPUTJSONL:
+		return ReadLinksJSONFile(filename)
+	default:
+		return nil, fmt.Errorf("not supported input:%s", filename)
+	}
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
@@ -0,0 +1,66 @@
+package goscraper
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadLinksJSON(t *testing.T) {
+	testLinks := Links{
+		*ls[1]: {Seq: 1, StatusCode: 200, Skipped: "deny:logout-url"},
+		*ls[0]: {Seq: 2, StatusCode: 404, Error: "Not Found"},
+	}
+	for _, outType := range []string{OptOUTPUTJSON, OptOUTPUTJSONL} {
+		var buf bytes.Buffer
+		sink, _ := NewLinkSink(&buf, outType)
+		for l, info := range testLinks {
+			sink.WriteLink(l, info)
+		}
+		sink.Close()
+		read, err := ReadLinksJSON(&buf)
+		if err != nil {
+			t.Errorf("error in ReadLinksJSON:%s:%v", outType, err)
+		}
+		if !reflect.DeepEqual(testLinks, read) {
+			t.Errorf("not matched %s,\nwant: %v,\nhave: %v", outType, testLinks, read)
+		}
+	}
+	if _, err := ReadLinksJSON(bytes.NewBufferString(`[{"from":1}]`)); err == nil {
+		t.Errorf("no error in invalid json")
+	}
+}
+
+func TestReadLinksCSV(t *testing.T) {
+	firstSeen := time.Date(2018, 6, 1, 10, 0, 0, 500, time.UTC)
+	form := &Form{Action: "http://example.com/save", Method: "POST"}
+	l := *ls[1]
+	l.AttrId, l.AttrOnClick, l.Text, l.Tag, l.Method, l.Selector = "id1", "go()", "a, \"b\"", "a", "GET", "a#id1"
+	testLinks := Links{
+		l:      {Seq: 3, Depth: 1, StatusCode: 200, ContentType: "text/html", FirstSeen: firstSeen, LastSeen: firstSeen, SeenCount: 2, ResponseTime: 1500 * time.Millisecond, Form: form},
+		*ls[0]: {Seq: 7, StatusCode: 503, Error: "Service Unavailable", Skipped: "deny:logout-url", Attempts: 3, Outcome: OutcomeTRANSIENT, ErrorClass: ClassSERVER},
+	}
+	var buf bytes.Buffer
+	if err := WriteLinks2CsvOrder(testLinks, &buf, OrderDISCOVERY); err != nil {
+		t.Fatalf("error in WriteLinks2CsvOrder:%v", err)
+	}
+	read, err := ReadLinksCSV(&buf)
+	if err != nil {
+		t.Errorf("error in ReadLinksCSV:%v", err)
+	}
+	if !reflect.DeepEqual(testLinks, read) {
+		t.Errorf("not matched,\nwant: %v,\nhave: %v", testLinks, read)
+	}
+
+	read, err = ReadLinksCSV(bytes.NewBufferString("from,to\nhttp://example.com,http://example.com/a\n"))
+	if err != nil || len(read) != 1 {
+		t.Errorf("not read old csv: %v, %v", read, err)
+	}
+	if _, err := ReadLinksCSV(bytes.NewBufferString("from,to,status\nhttp://example.com,http://example.com/a,ok\n")); err == nil {
+		t.Errorf("no error in invalid status")
+	}
+	if _, err := ReadLinksFile("output.txt"); err == nil {
+		t.Errorf("no error in unknown input")
+	}
+}
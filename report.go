@@ -0,0 +1,220 @@
+package goscraper
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MarkdownSink writes a Markdown table with a row per link.
+type MarkdownSink struct {
+	w io.Writer
+	n int
+}
+
+func NewMarkdownSink(w io.Writer) *MarkdownSink {
+	return &MarkdownSink{w: w}
+}
+
+func (s *MarkdownSink) header() error {
+	_, err := io.WriteString(s.w, "| no | from | to | method | text | depth | status | error | skipped_reason |\n"+
+		"| --- | --- | --- | --- | --- | --- | --- | --- | --- |\n")
+	return err
+}
+
+func (s *MarkdownSink) WriteLink(link Link, info *LinkInfo) error {
+	if info == nil {
+		info = &LinkInfo{}
+	}
+	if s.n == 0 {
+		if err := s.header(); err != nil {
+			return fmt.Errorf("failed to write markdown:%v", err)
+		}
+	}
+	s.n++
+	if _, err := fmt.Fprintf(s.w, "| %d | %s | %s | %s | %s | %d | %d | %s | %s |\n",
+		s.n,
+		markdownEscaper.Replace(link.From.String()),
+		markdownEscaper.Replace(link.To.String()),
+		link.Method,
+		markdownEscaper.Replace(strings.TrimSpace(link.Text)),
+		info.Depth,
+		info.StatusCode,
+		markdownEscaper.Replace(info.Error),
+		info.Skipped,
+	); err != nil {
+		return fmt.Errorf("failed to write markdown:%v", err)
+	}
+	return nil
+}
+
+func (s *MarkdownSink) Close() error {
+	if s.n == 0 {
+		if err := s.header(); err != nil {
+			return fmt.Errorf("failed to write markdown:%v", err)
+		}
+	}
+	return closeWriter(s.w)
+}
+
+// HtmlSink writes a standalone HTML report on Close, with the links grouped
+// by From page and a search box to filter them.
+type HtmlSink struct {
+	w     io.Writer
+	links Links
+}
+
+func NewHtmlSink(w io.Writer) *HtmlSink {
+	return &HtmlSink{w: w, links: make(Links)}
+}
+
+func (s *HtmlSink) WriteLink(link Link, info *LinkInfo) error {
+	if info == nil {
+		info = &LinkInfo{}
+	}
+	s.links[link] = info
+	return nil
+}
+
+type htmlPage struct {
+	From  string
+	Links []LinkRecord
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>goscraper report</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; font-size: 90%; }
+tr.error td { background: #fdd; }
+tr.skipped td { color: #999; }
+h2 { font-size: 100%; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>goscraper report</h1>
+<p>{{len .Pages}} pages, {{.Count}} links, generated at {{.Generated}}</p>
+<input id="search" type="search" placeholder="filter links" size="60">
+{{range .Pages}}<section class="page">
+<h2><a href="{{.From}}">{{.From}}</a></h2>
+<table>
+<tr><th>no</th><th>to</th><th>method</th><th>text</th><th>depth</th><th>status</th><th>error</th><th>skipped_reason</th></tr>
+{{range .Links}}<tr class="link{{if or .Error (ge .StatusCode 400)}} error{{end}}{{if .Skipped}} skipped{{end}}">
+<td>{{.Seq}}</td><td><a href="{{.To.String}}">{{.To.String}}</a></td><td>{{.Method}}</td><td>{{.Text}}</td><td>{{.Depth}}</td><td>{{.StatusCode}}</td><td>{{.Error}}</td><td>{{.Skipped}}</td>
+</tr>
+{{end}}</table>
+</section>
+{{end}}<script>
+document.getElementById("search").addEventListener("input", function() {
+  var q = this.value.toLowerCase();
+  document.querySelectorAll("section.page").forEach(function(page) {
+    var shown = 0;
+    page.querySelectorAll("tr.link").forEach(function(tr) {
+      var hit = page.querySelector("h2").textContent.toLowerCase().indexOf(q) >= 0 ||
+        tr.textContent.toLowerCase().indexOf(q) >= 0;
+      tr.style.display = hit ? "" : "none";
+      if (hit) { shown++; }
+    });
+    page.style.display = shown > 0 ? "" : "none";
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+func (s *HtmlSink) Close() error {
+	sorted, err := SortLinks(s.links, OrderSORTED)
+	if err != nil {
+		return err
+	}
+	var pages []*htmlPage
+	for _, l := range sorted {
+		from := l.From.String()
+		if len(pages) == 0 || pages[len(pages)-1].From != from {
+			pages = append(pages, &htmlPage{From: from})
+		}
+		page := pages[len(pages)-1]
+		page.Links = append(page.Links, LinkRecord{Link: l, LinkInfo: s.links[l]})
+	}
+	if err := htmlReport.Execute(s.w, map[string]interface{}{
+		"Pages":     pages,
+		"Count":     len(sorted),
+		"Generated": time.Now().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("failed to write html:%v", err)
+	}
+	return closeWriter(s.w)
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name      `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []*sitemapURL `xml:"url"`
+}
+
+// SitemapSink writes a sitemaps.org XML sitemap of UniqURL on Close. Links
+// which were skipped, failed or not fetched by GET are left out, as are URLs
+// not on Hosts, which LinkScraper sets to the host of Entry if empty. All
+// hosts are written if Hosts is empty.
+type SitemapSink struct {
+	Hosts []string
+	w     io.Writer
+	links Links
+}
+
+func NewSitemapSink(w io.Writer) *SitemapSink {
+	return &SitemapSink{w: w, links: make(Links)}
+}
+
+func (s *SitemapSink) WriteLink(link Link, info *LinkInfo) error {
+	if info == nil {
+		info = &LinkInfo{}
+	}
+	if info.Skipped != "" || info.Error != "" || info.StatusCode >= http.StatusBadRequest ||
+		(link.Method != "" && link.Method != http.MethodGet) ||
+		(link.To.Scheme != "http" && link.To.Scheme != "https") {
+		return nil
+	}
+	s.links[link] = info
+	return nil
+}
+
+func (s *SitemapSink) Close() error {
+	set := &sitemapURLSet{URLs: []*sitemapURL{}}
+	seen := make(map[string]bool)
+	for _, u := range UniqURL(s.links) {
+		if len(s.Hosts) > 0 && !hostIn(u, s.Hosts) {
+			continue
+		}
+		t := *u
+		t.Fragment = ""
+		loc := t.String()
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		set.URLs = append(set.URLs, &sitemapURL{Loc: loc})
+	}
+	sort.Slice(set.URLs, func(i, j int) bool { return set.URLs[i].Loc < set.URLs[j].Loc })
+	b, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sitemap:%v", err)
+	}
+	if _, err := io.WriteString(s.w, xml.Header+string(b)+"\n"); err != nil {
+		return fmt.Errorf("failed to write sitemap:%v", err)
+	}
+	return closeWriter(s.w)
+}
@@ -0,0 +1,125 @@
+package goscraper
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func reportLinks() Links {
+	newLink := func(from, to, method, text string) Link {
+		f, _ := url.Parse(from)
+		t, _ := url.Parse(to)
+		return Link{From: *f, To: *t, Method: method, Text: text}
+	}
+	return Links{
+		newLink("http://example.com/", "http://example.com/a#top", "GET", "a|b"):     {Seq: 1, StatusCode: 200},
+		newLink("http://example.com/", "http://example.com/b", "GET", "b"):           {Seq: 2, StatusCode: 404, Error: "Not Found"},
+		newLink("http://example.com/a", "http://example.com/logout", "GET", "out"):   {Seq: 3, Skipped: "deny:logout-url"},
+		newLink("http://example.com/a", "http://example.com/post", "POST", "submit"): {Seq: 4, StatusCode: 200},
+		newLink("http://example.com/a", "mailto:a@example.com", "GET", "mail"):       {Seq: 5},
+	}
+}
+
+func writeReport(t *testing.T, outType string) string {
+	var buf bytes.Buffer
+	sink, err := NewLinkSink(&buf, outType)
+	if err != nil {
+		t.Fatalf("error in NewLinkSink:%v", err)
+	}
+	links := reportLinks()
+	sorted, _ := SortLinks(links, OrderDISCOVERY)
+	for _, l := range sorted {
+		if err := sink.WriteLink(l, links[l]); err != nil {
+			t.Errorf("error in WriteLink:%v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("error in Close:%v", err)
+	}
+	return buf.String()
+}
+
+func TestMarkdownSink(t *testing.T) {
+	out := writeReport(t, OptOUTPUTMD)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("not matched lines: %v", lines)
+	}
+	expect := `| 1 | http://example.com/ | http://example.com/a#top | GET | a\|b | 0 | 200 |  |  |`
+	if lines[2] != expect {
+		t.Errorf("not matched row,\nwant: %s,\nhave: %s", expect, lines[2])
+	}
+}
+
+func TestHtmlSink(t *testing.T) {
+	out := writeReport(t, OptOUTPUTHTML)
+	for _, expect := range []string{
+		"2 pages, 5 links",
+		`<h2><a href="http://example.com/">http://example.com/</a></h2>`,
+		`<h2><a href="http://example.com/a">http://example.com/a</a></h2>`,
+		`<tr class="link error">`,
+		`<tr class="link skipped">`,
+		"a|b",
+		`id="search"`,
+	} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("not contains: %s", expect)
+		}
+	}
+}
+
+func TestSitemapSink(t *testing.T) {
+	expect := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>http://example.com/</loc>
+  </url>
+  <url>
+    <loc>http://example.com/a</loc>
+  </url>
+</urlset>
+`
+	if out := writeReport(t, OptOUTPUTXML); out != expect {
+		t.Errorf("not matched sitemap,\nwant: %s,\nhave: %s", expect, out)
+	}
+
+	// other hosts are left out by the host of Entry
+	var buf bytes.Buffer
+	lsc, err := NewLinkScraper(&Config{
+		Logger: logger,
+		Entry:  "http://example.com/",
+		Sink:   NewSitemapSink(&buf),
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if err := lsc.openSink(); err != nil {
+		t.Fatalf("error in openSink:%v", err)
+	}
+	links := reportLinks()
+	from, _ := url.Parse("http://example.com/a")
+	to, _ := url.Parse("http://other.example.com/x")
+	links[Link{From: *from, To: *to, Method: "GET"}] = &LinkInfo{Seq: 6, StatusCode: 200}
+	lsc.Links = links
+	if err := lsc.Output(); err != nil {
+		t.Errorf("error in Output:%v", err)
+	}
+	if buf.String() != expect {
+		t.Errorf("not matched sitemap,\nwant: %s,\nhave: %s", expect, buf.String())
+	}
+}
+
+func TestRegisterLinkSink(t *testing.T) {
+	RegisterLinkSink("test", func(w io.Writer) LinkSink { return NewJsonlSink(w) })
+	defer func() {
+		sinksMu.Lock()
+		delete(sinks, "test")
+		sinksMu.Unlock()
+	}()
+	if out := writeReport(t, "test"); len(strings.Split(strings.TrimSpace(out), "\n")) != 5 {
+		t.Errorf("not matched registered sink: %s", out)
+	}
+}
@@ -0,0 +1,197 @@
+package goscraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// classes of failed requests
+const (
+	ClassDNS       = "dns"
+	ClassRESET     = "reset"
+	ClassTIMEOUT   = "timeout"
+	ClassNETWORK   = "network"
+	ClassTHROTTLED = "throttled"
+	ClassSERVER    = "server"
+	ClassCLIENT    = "client"
+	ClassOTHER     = "other"
+)
+
+// final outcomes of requests
+const (
+	OutcomeOK        = "ok"
+	OutcomeTRANSIENT = "transient"
+	OutcomePERMANENT = "permanent"
+)
+
+// ClassifyError returns the class of a failed request by its status code
+// and error, and whether it may succeed if retried. class is empty if the
+// request did not fail.
+func ClassifyError(statusCode int, err error) (class string, transient bool) {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ClassTHROTTLED, true
+	case statusCode == http.StatusNotImplemented || statusCode == http.StatusHTTPVersionNotSupported:
+		return ClassSERVER, false
+	case statusCode >= 500:
+		return ClassSERVER, true
+	case statusCode >= 400:
+		return ClassCLIENT, false
+	case err == nil:
+		return "", false
+	}
+	// unwrap by hand, as errors.Is is not in go1.10
+	for {
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+			continue
+		case *net.DNSError:
+			return ClassDNS, e.IsTimeout || e.IsTemporary
+		case *net.OpError:
+			if e.Timeout() {
+				return ClassTIMEOUT, true
+			}
+			err = e.Err
+			continue
+		case *os.SyscallError:
+			err = e.Err
+			continue
+		case syscall.Errno:
+			if e == syscall.ECONNRESET || e == syscall.EPIPE {
+				return ClassRESET, true
+			}
+			return ClassNETWORK, true
+		case net.Error:
+			if e.Timeout() {
+				return ClassTIMEOUT, true
+			}
+			return ClassNETWORK, e.Temporary()
+		}
+		break
+	}
+	// closed by the server before the response
+	if err == io.EOF || err == io.ErrUnexpectedEOF || strings.Contains(err.Error(), "connection reset") {
+		return ClassRESET, true
+	}
+	return ClassOTHER, false
+}
+
+// outcome returns the outcome of a finished request.
+func outcome(statusCode int, err error) (string, string) {
+	class, transient := ClassifyError(statusCode, err)
+	switch {
+	case class == "":
+		return OutcomeOK, ""
+	case transient:
+		return OutcomeTRANSIENT, class
+	default:
+		return OutcomePERMANENT, class
+	}
+}
+
+// RetryPolicy retries GET and HEAD requests failed by a transient error,
+// after an exponential backoff with jitter from BaseDelay up to MaxDelay. A
+// POST is not retried, as it may be submitted twice. 429 and 503 are retried
+// by Politeness first, if set. A nil RetryPolicy does not retry.
+//
+// A retry is requested at once, and Wait delays it when it starts, as
+// Politeness does, not to block the callback of the failed request.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	mu      sync.Mutex
+	retries map[string]int
+	due     map[string]time.Time
+}
+
+// Next returns the delay before retrying the request of r failed by err, or
+// false if it should not be retried.
+func (p *RetryPolicy) Next(r *colly.Response, err error) (delay time.Duration, ok bool) {
+	if p == nil || (r.Request.Method != http.MethodGet && r.Request.Method != http.MethodHead) {
+		return 0, false
+	}
+	if _, transient := ClassifyError(r.StatusCode, err); !transient {
+		return 0, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.retries == nil {
+		p.retries = make(map[string]int)
+	}
+	key := fmt.Sprintf("%s %s", r.Request.Method, r.Request.URL.String())
+	n := p.retries[key]
+	if n >= p.MaxRetries {
+		return 0, false
+	}
+	p.retries[key]++
+	delay = p.Delay(n)
+	if p.due == nil {
+		p.due = make(map[string]time.Time)
+	}
+	p.due[key] = time.Now().Add(delay)
+	return delay, true
+}
+
+// Wait blocks until the delay given by Next for the retry of r is over.
+func (p *RetryPolicy) Wait(ctx context.Context, r *colly.Request) error {
+	if p == nil {
+		return nil
+	}
+	key := fmt.Sprintf("%s %s", r.Method, r.URL.String())
+	p.mu.Lock()
+	at, ok := p.due[key]
+	delete(p.due, key)
+	p.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return sleepContext(ctx, time.Until(at))
+}
+
+// Delay returns the backoff before the retry after n retries, a random
+// duration between the half and the whole of BaseDelay*2^n.
+func (p *RetryPolicy) Delay(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 0; i < n && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,167 @@
+package goscraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		status    int
+		err       error
+		class     string
+		transient bool
+	}{
+		{200, nil, "", false},
+		{429, errors.New("Too Many Requests"), ClassTHROTTLED, true},
+		{503, errors.New("Service Unavailable"), ClassSERVER, true},
+		{501, errors.New("Not Implemented"), ClassSERVER, false},
+		{404, errors.New("Not Found"), ClassCLIENT, false},
+		{0, &url.Error{Op: "Get", Err: &net.DNSError{Err: "no such host", Name: "example.invalid"}}, ClassDNS, false},
+		{0, &url.Error{Op: "Get", Err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}}, ClassDNS, true},
+		{0, &url.Error{Op: "Get", Err: &net.OpError{Op: "read", Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}}}, ClassRESET, true},
+		{0, &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}}, ClassNETWORK, true},
+		{0, &url.Error{Op: "Get", Err: &net.OpError{Op: "read", Err: timeoutError{}}}, ClassTIMEOUT, true},
+		{0, &url.Error{Op: "Get", Err: timeoutError{}}, ClassTIMEOUT, true},
+		{0, &url.Error{Op: "Get", Err: io.EOF}, ClassRESET, true},
+		{0, errors.New("unsupported protocol scheme"), ClassOTHER, false},
+	} {
+		class, transient := ClassifyError(tc.status, tc.err)
+		if class != tc.class || transient != tc.transient {
+			t.Errorf("not matched class of %d %v: %s, %v", tc.status, tc.err, class, transient)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for _, tc := range []struct {
+		n        int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	} {
+		for i := 0; i < 20; i++ {
+			if d := p.Delay(tc.n); d < tc.min || d > tc.max {
+				t.Errorf("not matched delay of %d: %v", tc.n, d)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	u, _ := url.Parse("http://example.com/flaky")
+	req := &colly.Request{URL: u, Method: http.MethodGet}
+	res := &colly.Response{Request: req, StatusCode: http.StatusInternalServerError}
+	p := &RetryPolicy{MaxRetries: 2, BaseDelay: 100 * time.Millisecond}
+
+	// Next does not wait, but the retry does
+	start := time.Now()
+	if _, ok := p.Next(res, nil); !ok || time.Since(start) > 20*time.Millisecond {
+		t.Errorf("not retried at once: %v, %v", ok, time.Since(start))
+	}
+	if err := p.Wait(context.Background(), req); err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("not waited: %v, %v", err, time.Since(start))
+	}
+	start = time.Now()
+	if err := p.Wait(context.Background(), req); err != nil || time.Since(start) > 20*time.Millisecond {
+		t.Errorf("waited without retry: %v, %v", err, time.Since(start))
+	}
+
+	p.Next(res, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Wait(ctx, req); err != context.Canceled {
+		t.Errorf("not canceled: %v", err)
+	}
+}
+
+func TestRetryScrape(t *testing.T) {
+	for _, parallelism := range []int{1, 2} {
+		testRetryScrape(t, parallelism)
+	}
+}
+
+func testRetryScrape(t *testing.T, parallelism int) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/flaky">flaky</a><a href="/down">down</a><a href="/gone">gone</a>` +
+				`<form action="/save" method="post"><input type="submit" value="save"></form>`))
+		case "/flaky":
+			if n < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "/down", "/save":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	lsc, err := NewLinkScraper(&Config{
+		Logger:   logger,
+		Entry:    ts.URL + "/",
+		Sink:     &recordSink{},
+		IsDoPost: true,
+		Retry:    &RetryPolicy{MaxRetries: 3, BaseDelay: 10 * time.Millisecond},
+
+		Parallelism: parallelism,
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if err := lsc.Scrape(); err != nil {
+		t.Errorf("error in Scrape:%v", err)
+	}
+	expect := map[string]LinkInfo{
+		"/flaky": {StatusCode: 200, Attempts: 3, Outcome: OutcomeOK},
+		"/down":  {StatusCode: 503, Attempts: 4, Outcome: OutcomeTRANSIENT, ErrorClass: ClassSERVER},
+		"/gone":  {StatusCode: 404, Attempts: 1, Outcome: OutcomePERMANENT, ErrorClass: ClassCLIENT},
+		"/save":  {StatusCode: 503, Attempts: 1, Outcome: OutcomeTRANSIENT, ErrorClass: ClassSERVER},
+	}
+	for l, info := range lsc.Links {
+		e, ok := expect[l.To.Path]
+		if !ok {
+			continue
+		}
+		if info.StatusCode != e.StatusCode || info.Attempts != e.Attempts || info.Outcome != e.Outcome || info.ErrorClass != e.ErrorClass {
+			t.Errorf("not matched outcome of %s:%d: %v", l.To.Path, parallelism, info)
+		}
+		delete(expect, l.To.Path)
+	}
+	if len(expect) != 0 {
+		t.Errorf("not found links:%d: %v", parallelism, expect)
+	}
+	if requests["/save"] != 1 {
+		t.Errorf("retried post:%d: %d", parallelism, requests["/save"])
+	}
+}
@@ -0,0 +1,200 @@
+package goscraper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	PresetLOGOUT      = "logout"
+	PresetDESTRUCTIVE = "destructive"
+	PresetNONE        = "none"
+)
+
+// SafetyRule denies links matching all of URL, Text, Field and Method.
+// URL, Text and Field are regexps, Field is matched against form field names
+// and Method against the link method or a form's _method override.
+type SafetyRule struct {
+	Name   string `mapstructure:"name"`
+	URL    string `mapstructure:"url"`
+	Text   string `mapstructure:"text"`
+	Field  string `mapstructure:"field"`
+	Method string `mapstructure:"method"`
+	url    *regexp.Regexp
+	text   *regexp.Regexp
+	field  *regexp.Regexp
+}
+
+var SafetyPresets = map[string][]*SafetyRule{
+	PresetLOGOUT: {
+		{Name: "logout-url", URL: `(?i)(^|[/_.?=-])(log|sign)[-_]?(out|off)([/_.?&=#;-]|$)`},
+		{Name: "logout-text", Text: `(?i)\b(log|sign)[ -]?(out|off)\b`},
+	},
+	PresetDESTRUCTIVE: {
+		{Name: "destructive-url", URL: `(?i)[/?&=_-](delete|destroy|remove|unsubscribe|deactivate|purge)\b`},
+		{Name: "destructive-text", Text: `(?i)\b(delete|destroy|remove|unsubscribe|deactivate|purge|close account|cancel account)\b`},
+		{Name: "destructive-field", Field: `(?i)^_?(delete|destroy|remove|confirm_delete)`},
+		{Name: "destructive-method", Method: "DELETE"},
+	},
+}
+
+var DefaultSafetyPresets = []string{PresetLOGOUT, PresetDESTRUCTIVE}
+
+type SafetyGuard struct {
+	Rules []*SafetyRule
+}
+
+func LoadSafetyRules(filename string) (rules []*SafetyRule, err error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read safety rules:%s:%v", filename, err)
+	}
+	if err := v.UnmarshalKey("deny", &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal safety rules:%s:%v", filename, err)
+	}
+	return rules, nil
+}
+
+// NewSafetyGuard returns a guard with the rules of presets followed by rules.
+// Preset "none" adds nothing, so that the guard can be turned off.
+func NewSafetyGuard(presets []string, rules []*SafetyRule) (*SafetyGuard, error) {
+	g := &SafetyGuard{}
+	for _, preset := range presets {
+		preset = strings.TrimSpace(preset)
+		if preset == "" || preset == PresetNONE {
+			continue
+		}
+		presetRules, ok := SafetyPresets[preset]
+		if !ok {
+			return nil, fmt.Errorf("not supported safety preset:%s", preset)
+		}
+		for _, rule := range presetRules {
+			r := *rule
+			g.Rules = append(g.Rules, &r)
+		}
+	}
+	g.Rules = append(g.Rules, rules...)
+	for _, rule := range g.Rules {
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func compileRule(name, expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid safety rule:%s:%s:%v", name, expr, err)
+	}
+	return re, nil
+}
+
+func (rule *SafetyRule) compile() (err error) {
+	if rule.URL == "" && rule.Text == "" && rule.Field == "" && rule.Method == "" {
+		return fmt.Errorf("empty safety rule:%s", rule.Name)
+	}
+	if rule.url, err = compileRule(rule.Name, rule.URL); err != nil {
+		return err
+	}
+	if rule.text, err = compileRule(rule.Name, rule.Text); err != nil {
+		return err
+	}
+	rule.field, err = compileRule(rule.Name, rule.Field)
+	return err
+}
+
+func linkMethod(link *Link, form *Form) string {
+	if form != nil {
+		for _, field := range form.Fields {
+			if field.Name == "_method" && field.Value != "" {
+				return strings.ToUpper(field.Value)
+			}
+		}
+	}
+	return link.Method
+}
+
+func (rule *SafetyRule) Matches(link *Link, form *Form) bool {
+	if rule.url != nil && !rule.url.MatchString(link.To.String()) {
+		return false
+	}
+	if rule.text != nil && !rule.text.MatchString(link.Text) {
+		return false
+	}
+	if rule.Method != "" && !strings.EqualFold(rule.Method, linkMethod(link, form)) {
+		return false
+	}
+	if rule.field != nil {
+		if form == nil {
+			return false
+		}
+		for _, field := range form.Fields {
+			if rule.field.MatchString(field.Name) {
+				return true
+			}
+		}
+		for _, submit := range form.Submits {
+			if submit.Name != "" && rule.field.MatchString(submit.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Deny returns the reason to skip link, which is the name of the first
+// matching rule. A form is also denied if a rule matches the formaction of
+// any of its submits.
+func (g *SafetyGuard) Deny(link *Link, form *Form) (reason string, ok bool) {
+	if g == nil {
+		return "", false
+	}
+	if reason, ok := g.deny(link, form); ok {
+		return reason, true
+	}
+	if form == nil {
+		return "", false
+	}
+	for _, submit := range form.Submits {
+		if submit.FormAction == "" {
+			continue
+		}
+		to, err := link.To.Parse(submit.FormAction)
+		if err != nil {
+			continue
+		}
+		l := *link
+		l.To = *to
+		if submit.FormMethod != "" {
+			l.Method = formMethod(submit.FormMethod)
+		}
+		if submit.Text != "" {
+			l.Text = submit.Text
+		}
+		if reason, ok := g.deny(&l, form); ok {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+func (g *SafetyGuard) deny(link *Link, form *Form) (reason string, ok bool) {
+	for _, rule := range g.Rules {
+		if rule.Matches(link, form) {
+			if rule.Name == "" {
+				return "deny", true
+			}
+			return "deny:" + rule.Name, true
+		}
+	}
+	return "", false
+}
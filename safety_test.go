@@ -0,0 +1,104 @@
+package goscraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafetyGuard(t *testing.T) {
+	rules, err := LoadSafetyRules(filepath.Join("testdata", "safetyrules.toml"))
+	if err != nil {
+		t.Fatalf("error in LoadSafetyRules:%v", err)
+	}
+	g, err := NewSafetyGuard(DefaultSafetyPresets, rules)
+	if err != nil {
+		t.Fatalf("error in NewSafetyGuard:%v", err)
+	}
+
+	newLink := func(to, text, method string) *Link {
+		u, _ := url.Parse(to)
+		return &Link{To: *u, Text: text, Method: method}
+	}
+	tests := []struct {
+		link   *Link
+		form   *Form
+		expect string
+	}{
+		{newLink("http://example.com/users/1", "profile", http.MethodGet), nil, ""},
+		{newLink("http://example.com/logout", "bye", http.MethodGet), nil, "deny:logout-url"},
+		{newLink("http://example.com/user/sign_out?next=/", "bye", http.MethodGet), nil, "deny:logout-url"},
+		{newLink("http://example.com/auth.php?action=LogOff", "bye", http.MethodGet), nil, "deny:logout-url"},
+		{newLink("http://example.com/catalog-outlet", "outlet", http.MethodGet), nil, ""},
+		{newLink("http://example.com/blog_offers", "offers", http.MethodGet), nil, ""},
+		{newLink("http://example.com/changelog-office", "office", http.MethodGet), nil, ""},
+		{newLink("http://example.com/session", "Sign out", http.MethodGet), nil, "deny:logout-text"},
+		{newLink("http://example.com/posts/1?action=delete", "x", http.MethodGet), nil, "deny:destructive-url"},
+		{newLink("http://example.com/mail", "Unsubscribe", http.MethodGet), nil, "deny:destructive-text"},
+		{newLink("http://example.com/posts/1", "", http.MethodPost), &Form{Fields: []*FormField{{Name: "_method", Value: "delete"}}}, "deny:destructive-method"},
+		{newLink("http://example.com/posts/1", "", http.MethodPost), &Form{Submits: []*FormSubmit{{Name: "confirm_delete"}}}, "deny:destructive-field"},
+		{newLink("http://example.com/admin/users", "users", http.MethodGet), nil, "deny:admin"},
+		{newLink("http://example.com/password", "", http.MethodPost), &Form{Fields: []*FormField{{Name: "new_password"}}}, "deny:reset-password"},
+		{newLink("http://example.com/password", "", http.MethodGet), &Form{Fields: []*FormField{{Name: "new_password"}}}, ""},
+		{newLink("http://example.com/posts/1", "", http.MethodPost), &Form{Submits: []*FormSubmit{{Text: "save"}, {Text: "x", FormAction: "/posts/1/delete"}}}, "deny:destructive-url"},
+		{newLink("http://example.com/posts/1", "", http.MethodPost), &Form{Submits: []*FormSubmit{{Text: "save", FormAction: "/posts/1/edit"}}}, ""},
+	}
+	for _, test := range tests {
+		reason, _ := g.Deny(test.link, test.form)
+		if reason != test.expect {
+			t.Errorf("not matched: %s %s,\nwant: %v,\nhave: %v", test.link.Method, test.link.To.String(), test.expect, reason)
+		}
+	}
+
+	if g, err := NewSafetyGuard([]string{PresetNONE}, nil); err != nil || len(g.Rules) != 0 {
+		t.Errorf("not empty guard: %v, %v", g, err)
+	}
+	if _, err := NewSafetyGuard([]string{"unknown"}, nil); err == nil {
+		t.Errorf("no error in unknown preset")
+	}
+	if _, err := NewSafetyGuard(nil, []*SafetyRule{{Name: "empty"}}); err == nil {
+		t.Errorf("no error in empty rule")
+	}
+}
+
+func TestSafetyScrape(t *testing.T) {
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/about">about</a><a href="/logout">logout</a>
+<form action="/posts/1" method="post"><input type="hidden" name="_method" value="delete"><button>x</button></form>`)
+		}
+	}))
+	defer ts.Close()
+
+	safety, _ := NewSafetyGuard(DefaultSafetyPresets, nil)
+	lsc, err := NewLinkScraper(&Config{
+		Logger:   logger,
+		IsDoPost: true,
+		Safety:   safety,
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	lsc.registHandler()
+	if err := lsc.Collector.Visit(ts.URL + "/"); err != nil {
+		t.Errorf("error in Visit:%v", err)
+	}
+
+	skipped := map[string]string{}
+	for link, info := range lsc.Links {
+		skipped[link.To.Path] = info.Skipped
+	}
+	expect := map[string]string{"/about": "", "/logout": "deny:logout-url", "/posts/1": "deny:destructive-method"}
+	if fmt.Sprint(expect) != fmt.Sprint(skipped) {
+		t.Errorf("not matched skipped,\nwant: %v,\nhave: %v", expect, skipped)
+	}
+	if fmt.Sprint(requested) != "[GET / GET /about]" {
+		t.Errorf("requested denied links: %v", requested)
+	}
+}
@@ -0,0 +1,228 @@
+package goscraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+const (
+	SessionCONTAINS = "contains"
+	SessionREGEXP   = "regexp"
+	SessionSELECTOR = "selector"
+	SessionREDIRECT = "redirect"
+	SessionSTATUS   = "status"
+	SessionCOOKIE   = "cookie"
+)
+
+// SessionCheck tells whether a response was served to a logged-in user.
+type SessionCheck interface {
+	IsLoggedIn(c *colly.Collector, r *colly.Response) bool
+}
+
+type ContainsCheck struct {
+	Text string
+}
+
+func (s *ContainsCheck) IsLoggedIn(_ *colly.Collector, r *colly.Response) bool {
+	return bytes.Contains(r.Body, []byte(s.Text))
+}
+
+type RegexpCheck struct {
+	Regexp *regexp.Regexp
+}
+
+func (s *RegexpCheck) IsLoggedIn(_ *colly.Collector, r *colly.Response) bool {
+	return s.Regexp.Match(r.Body)
+}
+
+// SelectorCheck is logged in if the page has an element matching Selector,
+// e.g. a logout button.
+type SelectorCheck struct {
+	Selector string
+}
+
+func (s *SelectorCheck) IsLoggedIn(_ *colly.Collector, r *colly.Response) bool {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+	if err != nil {
+		return false
+	}
+	return doc.Find(s.Selector).Length() > 0
+}
+
+// RedirectCheck is logged out if the request ended up on LoginURL. The
+// scheme, host and path are compared, ignoring the query string and a
+// trailing slash.
+type RedirectCheck struct {
+	LoginURL string
+}
+
+func (s *RedirectCheck) IsLoggedIn(_ *colly.Collector, r *colly.Response) bool {
+	login, err := r.Request.URL.Parse(s.LoginURL)
+	if err != nil {
+		return true
+	}
+	return !sameLocation(r.Request.URL, login)
+}
+
+func sameLocation(u1, u2 *url.URL) bool {
+	return strings.EqualFold(u1.Scheme, u2.Scheme) &&
+		strings.EqualFold(u1.Host, u2.Host) &&
+		strings.TrimSuffix(u1.Path, "/") == strings.TrimSuffix(u2.Path, "/")
+}
+
+// StatusCheck is logged out if the response status is one of Codes, e.g. 401.
+type StatusCheck struct {
+	Codes []int
+}
+
+func (s *StatusCheck) IsLoggedIn(_ *colly.Collector, r *colly.Response) bool {
+	for _, code := range s.Codes {
+		if r.StatusCode == code {
+			return false
+		}
+	}
+	return true
+}
+
+// CookieCheck is logged in while the collector has a cookie named Name for
+// the requested URL.
+type CookieCheck struct {
+	Name string
+}
+
+func (s *CookieCheck) IsLoggedIn(c *colly.Collector, r *colly.Response) bool {
+	for _, cookie := range c.Cookies(r.Request.URL.String()) {
+		if cookie.Name == s.Name && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// AllChecks is logged in only if all of the checks are.
+type AllChecks []SessionCheck
+
+func (s AllChecks) IsLoggedIn(c *colly.Collector, r *colly.Response) bool {
+	for _, check := range s {
+		if !check.IsLoggedIn(c, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func NewSessionCheck(kind, value string) (SessionCheck, error) {
+	switch kind {
+	case "", SessionCONTAINS:
+		return &ContainsCheck{Text: value}, nil
+	case SessionREGEXP:
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session regexp:%s:%v", value, err)
+		}
+		return &RegexpCheck{Regexp: re}, nil
+	case SessionSELECTOR:
+		return &SelectorCheck{Selector: value}, nil
+	case SessionREDIRECT:
+		return &RedirectCheck{LoginURL: value}, nil
+	case SessionSTATUS:
+		check := &StatusCheck{}
+		for _, code := range strings.Split(value, ",") {
+			c, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return nil, fmt.Errorf("invalid session status:%s:%v", code, err)
+			}
+			check.Codes = append(check.Codes, c)
+		}
+		return check, nil
+	case SessionCOOKIE:
+		return &CookieCheck{Name: value}, nil
+	default:
+		return nil, fmt.Errorf("not supported session check:%s", kind)
+	}
+}
+
+// sessionState keeps the crawl from following pages served while logged
+// out, and caps how often it logs in again.
+type sessionState struct {
+	mu        sync.Mutex
+	loggingIn bool
+	relogins  int
+	loggedOut map[*colly.Request]bool
+	retried   map[string]bool
+}
+
+func newSessionState() *sessionState {
+	return &sessionState{
+		loggedOut: make(map[*colly.Request]bool),
+		retried:   make(map[string]bool),
+	}
+}
+
+func (s *sessionState) inLogin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loggingIn
+}
+
+func (s *sessionState) setInLogin(in bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loggingIn = in
+}
+
+func (s *sessionState) begin(max int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.relogins >= max {
+		return false
+	}
+	s.relogins++
+	return true
+}
+
+func (s *sessionState) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.relogins
+}
+
+// retry records the request of r to be retried after a relogin, and returns
+// false if it was already, as the check may fail on the page anyway.
+func (s *sessionState) retry(r *colly.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fmt.Sprintf("%s %s", r.Method, r.URL.String())
+	if s.retried[key] {
+		return false
+	}
+	s.retried[key] = true
+	return true
+}
+
+// markLoggedOut drops the links of r, which is replaced by its retry.
+func (s *sessionState) markLoggedOut(r *colly.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loggedOut[r] = true
+}
+
+func (s *sessionState) isLoggedOut(r *colly.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loggedOut[r]
+}
+
+func (s *sessionState) done(r *colly.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.loggedOut, r)
+}
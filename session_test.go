@@ -0,0 +1,202 @@
+package goscraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestNewSessionCheck(t *testing.T) {
+	tests := []struct {
+		kind   string
+		value  string
+		expect SessionCheck
+	}{
+		{"", "loggedin", &ContainsCheck{Text: "loggedin"}},
+		{SessionSELECTOR, "a.logout", &SelectorCheck{Selector: "a.logout"}},
+		{SessionSTATUS, "401, 403", &StatusCheck{Codes: []int{401, 403}}},
+		{SessionCOOKIE, "session", &CookieCheck{Name: "session"}},
+	}
+	for _, test := range tests {
+		check, err := NewSessionCheck(test.kind, test.value)
+		if err != nil {
+			t.Errorf("error in NewSessionCheck:%v", err)
+		}
+		if !reflect.DeepEqual(test.expect, check) {
+			t.Errorf("not matched: %s,\nwant: %v,\nhave: %v", test.kind, test.expect, check)
+		}
+	}
+	for _, kind := range []string{SessionREGEXP, SessionSTATUS, "unknown"} {
+		if _, err := NewSessionCheck(kind, "("); err == nil {
+			t.Errorf("no error in invalid check: %s", kind)
+		}
+	}
+}
+
+func TestSessionCheck(t *testing.T) {
+	login, _ := NewSessionCheck(SessionREDIRECT, "http://example.com/login?next=/")
+	re, _ := NewSessionCheck(SessionREGEXP, `Hello, \w+`)
+	page := &colly.Response{StatusCode: 200, Body: []byte(`<p>Hello, user</p><a class="logout">logout</a>`)}
+	page.Request = &colly.Request{}
+	page.Request.URL, _ = page.Request.URL.Parse("http://example.com/users")
+	denied := &colly.Response{StatusCode: 401, Body: []byte(`<form action="/login"></form>`)}
+	denied.Request = &colly.Request{}
+	denied.Request.URL, _ = denied.Request.URL.Parse("http://example.com/login?next=/users")
+
+	checks := []SessionCheck{
+		login,
+		re,
+		&SelectorCheck{Selector: "a.logout"},
+		&StatusCheck{Codes: []int{401}},
+		AllChecks{login, re},
+	}
+	for _, check := range checks {
+		if !check.IsLoggedIn(nil, page) {
+			t.Errorf("not logged in: %#v", check)
+		}
+		if check.IsLoggedIn(nil, denied) {
+			t.Errorf("logged in: %#v", check)
+		}
+	}
+
+	for _, to := range []string{"http://example.com/login-help", "http://example.com/loginfoo", "https://example.com/login", "http://example.org/login"} {
+		page.Request.URL, _ = page.Request.URL.Parse(to)
+		if !login.IsLoggedIn(nil, page) {
+			t.Errorf("not logged in: %s", to)
+		}
+	}
+	denied.Request.URL, _ = denied.Request.URL.Parse("http://EXAMPLE.com/login/")
+	if login.IsLoggedIn(nil, denied) {
+		t.Errorf("logged in: %s", denied.Request.URL)
+	}
+}
+
+func TestRelogin(t *testing.T) {
+	valid := false
+	logins := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/login" {
+			logins++
+			valid = true
+			return
+		}
+		if !valid {
+			fmt.Fprint(w, `<a href="/login">login</a>`)
+			return
+		}
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `loggedin <a href="/a">a</a>`)
+		case "/a":
+			valid = false // session expires
+			fmt.Fprint(w, `loggedin <a href="/b">b</a>`)
+		case "/b":
+			fmt.Fprint(w, `loggedin <a href="/c">c</a>`)
+		default:
+			fmt.Fprint(w, `loggedin`)
+		}
+	}))
+	defer ts.Close()
+
+	lsc, err := NewLinkScraper(&Config{
+		Collector: colly.NewCollector(),
+		Logger:    logger,
+		LoginURL:  ts.URL + "/login",
+		Entry:     ts.URL + "/",
+		Session:   &ContainsCheck{Text: "loggedin"},
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	lsc.registHandler()
+	if err := lsc.Login(); err != nil {
+		t.Errorf("error in Login:%v", err)
+	}
+	if err := lsc.Collector.Visit(lsc.Entry); err != nil {
+		t.Errorf("error in Visit:%v", err)
+	}
+
+	var tos []string
+	for link := range lsc.Links {
+		tos = append(tos, link.To.Path)
+	}
+	sort.Strings(tos)
+	if expect := []string{"/a", "/b", "/c"}; !reflect.DeepEqual(expect, tos) {
+		t.Errorf("not matched links,\nwant: %v,\nhave: %v", expect, tos)
+	}
+	if logins != 2 || lsc.ReloginCount() != 1 {
+		t.Errorf("not matched logins: %d, relogins: %d", logins, lsc.ReloginCount())
+	}
+}
+
+func TestSessionCheckNotLoggedOut(t *testing.T) {
+	var mu sync.Mutex
+	logins := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			mu.Lock()
+			logins++
+			mu.Unlock()
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/missing">missing</a><a href="/plain">plain</a>`)
+		case "/a":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/b">b</a>`)
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, `plain`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	expect := []string{"/a", "/b", "/missing", "/plain"}
+	for _, tc := range []struct {
+		session  SessionCheck
+		relogins int
+	}{
+		// no check by default
+		{nil, 0},
+		// a wrong marker retries the html pages once, and keeps their links
+		{&ContainsCheck{Text: "loggedin"}, 2},
+	} {
+		mu.Lock()
+		logins = 0
+		mu.Unlock()
+		lsc, err := NewLinkScraper(&Config{
+			Logger:     logger,
+			LoginURL:   ts.URL + "/login",
+			Entry:      ts.URL + "/",
+			Session:    tc.session,
+			MaxRelogin: 10,
+			Sink:       &recordSink{},
+		})
+		if err != nil {
+			t.Fatalf("error in NewLinkScraper:%v", err)
+		}
+		if err := lsc.Scrape(); err != nil {
+			t.Errorf("error in Scrape:%v", err)
+		}
+		var tos []string
+		for link := range lsc.Links {
+			tos = append(tos, link.To.Path)
+		}
+		sort.Strings(tos)
+		if !reflect.DeepEqual(expect, tos) {
+			t.Errorf("not matched links of %#v,\nwant: %v,\nhave: %v", tc.session, expect, tos)
+		}
+		if lsc.ReloginCount() != tc.relogins {
+			t.Errorf("not matched relogins of %#v: %d", tc.session, lsc.ReloginCount())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package goscraper
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+const (
+	SimilarityEXACT       = "exact"
+	SimilarityKEYSET      = "keyset"
+	SimilaritySIGNIFICANT = "significant"
+	SimilarityTEMPLATE    = "template"
+	SimilarityFRAGMENT    = "fragment"
+)
+
+type URLSimilarity interface {
+	Similar(u1, u2 *url.URL) bool
+}
+
+// KeyedURLSimilarity maps each URL to a key, with similar URLs sharing the
+// same key, so links can be deduplicated with a hash index instead of
+// comparing every pair.
+type KeyedURLSimilarity interface {
+	URLSimilarity
+	Key(u *url.URL) string
+}
+
+type URLSimilarityFunc func(u1, u2 *url.URL) bool
+
+func (f URLSimilarityFunc) Similar(u1, u2 *url.URL) bool {
+	return f(u1, u2)
+}
+
+var DefaultURLSimilarity URLSimilarity = KeySetSimilarity{}
+
+type ExactSimilarity struct{}
+
+func (ExactSimilarity) Similar(u1, u2 *url.URL) bool {
+	return u1.String() == u2.String()
+}
+
+func (ExactSimilarity) Key(u *url.URL) string {
+	return u.String()
+}
+
+// KeySetSimilarity treats URLs with the same host, path and set of query keys
+// as similar, whatever the query values are.
+type KeySetSimilarity struct{}
+
+func (KeySetSimilarity) Similar(u1, u2 *url.URL) bool {
+	return isSimilerURL(u1, u2)
+}
+
+func (KeySetSimilarity) Key(u *url.URL) string {
+	return similarityKey(u)
+}
+
+func similarityKey(u *url.URL) string {
+	q := u.Query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("%q %q %q", u.Host, u.Path, keys)
+}
+
+// SignificantKeySimilarity is KeySetSimilarity, but the values of Keys must
+// also be the same, e.g. for routers dispatching on ?action=.
+type SignificantKeySimilarity struct {
+	Keys []string
+}
+
+func (s SignificantKeySimilarity) Similar(u1, u2 *url.URL) bool {
+	if !isSimilerURL(u1, u2) {
+		return false
+	}
+	q1 := u1.Query()
+	q2 := u2.Query()
+	for _, k := range s.Keys {
+		if q1.Get(k) != q2.Get(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s SignificantKeySimilarity) Key(u *url.URL) string {
+	q := u.Query()
+	vs := make([]string, len(s.Keys))
+	for i, k := range s.Keys {
+		vs[i] = q.Get(k)
+	}
+	return fmt.Sprintf("%s %q", similarityKey(u), vs)
+}
+
+// PathTemplateSimilarity treats URLs matching the same URLPattern as similar,
+// e.g. /users/123/edit and /users/456/edit.
+type PathTemplateSimilarity struct{}
+
+func (s PathTemplateSimilarity) Similar(u1, u2 *url.URL) bool {
+	return s.Key(u1) == s.Key(u2)
+}
+
+func (PathTemplateSimilarity) Key(u *url.URL) string {
+	return NewURLPattern(u).Key()
+}
+
+// FragmentSimilarity also requires the same fragment, for sites routing on
+// #/path. Base defaults to KeySetSimilarity.
+type FragmentSimilarity struct {
+	Base KeyedURLSimilarity
+}
+
+func (s FragmentSimilarity) base() KeyedURLSimilarity {
+	if s.Base == nil {
+		return KeySetSimilarity{}
+	}
+	return s.Base
+}
+
+func (s FragmentSimilarity) Similar(u1, u2 *url.URL) bool {
+	return u1.Fragment == u2.Fragment && s.base().Similar(u1, u2)
+}
+
+func (s FragmentSimilarity) Key(u *url.URL) string {
+	return fmt.Sprintf("%s %q", s.base().Key(u), u.Fragment)
+}
+
+func NewURLSimilarity(name string, significantKeys []string) (URLSimilarity, error) {
+	switch name {
+	case SimilarityEXACT:
+		return ExactSimilarity{}, nil
+	case "", SimilarityKEYSET:
+		return KeySetSimilarity{}, nil
+	case SimilaritySIGNIFICANT:
+		return SignificantKeySimilarity{Keys: significantKeys}, nil
+	case SimilarityTEMPLATE:
+		return PathTemplateSimilarity{}, nil
+	case SimilarityFRAGMENT:
+		return FragmentSimilarity{}, nil
+	default:
+		return nil, fmt.Errorf("not supported similarity:%s", name)
+	}
+}
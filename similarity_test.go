@@ -0,0 +1,46 @@
+package goscraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestURLSimilarity(t *testing.T) {
+	tests := []struct {
+		sim    URLSimilarity
+		u1     string
+		u2     string
+		expect bool
+	}{
+		{ExactSimilarity{}, "http://example.com?a=1", "http://example.com?a=1", true},
+		{ExactSimilarity{}, "http://example.com?a=1", "http://example.com?a=2", false},
+		{KeySetSimilarity{}, "http://example.com?a=1&b=2", "http://example.com?b=3&a=4", true},
+		{KeySetSimilarity{}, "http://example.com/users/1", "http://example.com/users/2", false},
+		{SignificantKeySimilarity{Keys: []string{"action"}}, "http://example.com?action=edit&id=1", "http://example.com?action=edit&id=2", true},
+		{SignificantKeySimilarity{Keys: []string{"action"}}, "http://example.com?action=edit&id=1", "http://example.com?action=delete&id=1", false},
+		{PathTemplateSimilarity{}, "http://example.com/users/1/edit", "http://example.com/users/2/edit", true},
+		{PathTemplateSimilarity{}, "http://example.com/users/1/edit", "http://example.com/users/new/edit", false},
+		{FragmentSimilarity{}, "http://example.com/#/users", "http://example.com/#/users", true},
+		{FragmentSimilarity{}, "http://example.com/#/users", "http://example.com/#/posts", false},
+	}
+	for _, test := range tests {
+		u1, _ := url.Parse(test.u1)
+		u2, _ := url.Parse(test.u2)
+		if test.sim.Similar(u1, u2) != test.expect {
+			t.Errorf("not matched: %T: %s, %s, want: %v", test.sim, test.u1, test.u2, test.expect)
+		}
+	}
+}
+
+func TestNewURLSimilarity(t *testing.T) {
+	if _, err := NewURLSimilarity("unknown", nil); err == nil {
+		t.Errorf("no error in unknown similarity")
+	}
+	sim, err := NewURLSimilarity(SimilaritySIGNIFICANT, []string{"action"})
+	if err != nil {
+		t.Errorf("error in NewURLSimilarity:%v", err)
+	}
+	if s, ok := sim.(SignificantKeySimilarity); !ok || s.Keys[0] != "action" {
+		t.Errorf("not matched: %v", sim)
+	}
+}
@@ -0,0 +1,299 @@
+package goscraper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// OutFile to write links to stdout.
+const OutSTDOUT = "-"
+
+// LinkSink receives links one by one, e.g. as the crawl finds them.
+type LinkSink interface {
+	WriteLink(link Link, info *LinkInfo) error
+	Close() error
+}
+
+var csvHeader = []string{
+	"no",
+	"seq",
+	"from",
+	"to",
+	"attr_id",
+	"onclick",
+	"text",
+	"tag",
+	"method",
+	"selector",
+	"depth",
+	"status",
+	"content_type",
+	"error",
+	"first_seen",
+	"last_seen",
+	"seen_count",
+	"response_time",
+	"form",
+	"skipped_reason",
+	"attempts",
+	"outcome",
+	"error_class",
+}
+
+func csvRecord(no int, link Link, info *LinkInfo) ([]string, error) {
+	if info == nil {
+		info = &LinkInfo{}
+	}
+	form := ""
+	if info.Form != nil {
+		b, err := json.Marshal(info.Form)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal form:%v", err)
+		}
+		form = string(b)
+	}
+	return []string{
+		fmt.Sprintf("%d", no),
+		fmt.Sprintf("%d", info.Seq),
+		link.From.String(),
+		link.To.String(),
+		link.AttrId,
+		link.AttrOnClick,
+		link.Text,
+		link.Tag,
+		link.Method,
+		link.Selector,
+		fmt.Sprintf("%d", info.Depth),
+		fmt.Sprintf("%d", info.StatusCode),
+		info.ContentType,
+		info.Error,
+		formatTime(info.FirstSeen),
+		formatTime(info.LastSeen),
+		fmt.Sprintf("%d", info.SeenCount),
+		info.ResponseTime.String(),
+		form,
+		info.Skipped,
+		fmt.Sprintf("%d", info.Attempts),
+		info.Outcome,
+		info.ErrorClass,
+	}, nil
+}
+
+func closeWriter(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// CsvSink writes a row per link, flushed at once so the file can be tailed.
+type CsvSink struct {
+	w  io.Writer
+	cw *csv.Writer
+	n  int
+}
+
+func NewCsvSink(w io.Writer) *CsvSink {
+	return &CsvSink{w: w, cw: csv.NewWriter(w)}
+}
+
+func (s *CsvSink) WriteLink(link Link, info *LinkInfo) error {
+	if s.n == 0 {
+		s.cw.Write(csvHeader)
+	}
+	s.n++
+	record, err := csvRecord(s.n, link, info)
+	if err != nil {
+		return err
+	}
+	if err := s.cw.Write(record); err != nil {
+		return fmt.Errorf("failed to write csv record:%v", err)
+	}
+	s.cw.Flush()
+	if err := s.cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv:%v", err)
+	}
+	return nil
+}
+
+// Close writes the header if no link was written, and closes the writer if
+// it is an io.Closer.
+func (s *CsvSink) Close() error {
+	if s.n == 0 {
+		s.cw.Write(csvHeader)
+		s.cw.Flush()
+		if err := s.cw.Error(); err != nil {
+			return fmt.Errorf("failed to write csv:%v", err)
+		}
+	}
+	return closeWriter(s.w)
+}
+
+// JsonSink writes a JSON array of LinkRecord, which is complete on Close.
+type JsonSink struct {
+	w io.Writer
+	n int
+}
+
+func NewJsonSink(w io.Writer) *JsonSink {
+	return &JsonSink{w: w}
+}
+
+func (s *JsonSink) WriteLink(link Link, info *LinkInfo) error {
+	b, err := json.Marshal(LinkRecord{Link: link, LinkInfo: info})
+	if err != nil {
+		return fmt.Errorf("failed to marshal:%v", err)
+	}
+	sep := ","
+	if s.n == 0 {
+		sep = "["
+	}
+	s.n++
+	if _, err := s.w.Write(append([]byte(sep), b...)); err != nil {
+		return fmt.Errorf("failed to write json:%v", err)
+	}
+	return nil
+}
+
+func (s *JsonSink) Close() error {
+	end := "]"
+	if s.n == 0 {
+		end = "[]"
+	}
+	if _, err := io.WriteString(s.w, end); err != nil {
+		return fmt.Errorf("failed to write json:%v", err)
+	}
+	return closeWriter(s.w)
+}
+
+// JsonlSink writes a LinkRecord per line.
+type JsonlSink struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewJsonlSink(w io.Writer) *JsonlSink {
+	return &JsonlSink{w: w, enc: json.NewEncoder(w)}
+}
+
+func (s *JsonlSink) WriteLink(link Link, info *LinkInfo) error {
+	if err := s.enc.Encode(LinkRecord{Link: link, LinkInfo: info}); err != nil {
+		return fmt.Errorf("failed to write jsonl:%v", err)
+	}
+	return nil
+}
+
+func (s *JsonlSink) Close() error {
+	return closeWriter(s.w)
+}
+
+// LinkSinkFactory makes a sink writing to w.
+type LinkSinkFactory func(w io.Writer) LinkSink
+
+var (
+	sinksMu sync.RWMutex
+	sinks   = map[string]LinkSinkFactory{
+		OptOUTPUTCSV:   func(w io.Writer) LinkSink { return NewCsvSink(w) },
+		OptOUTPUTJSON:  func(w io.Writer) LinkSink { return NewJsonSink(w) },
+		OptOUTPUTJSONL: func(w io.Writer) LinkSink { return NewJsonlSink(w) },
+		OptOUTPUTMD:    func(w io.Writer) LinkSink { return NewMarkdownSink(w) },
+		OptOUTPUTHTML:  func(w io.Writer) LinkSink { return NewHtmlSink(w) },
+		OptOUTPUTXML:   func(w io.Writer) LinkSink { return NewSitemapSink(w) },
+	}
+)
+
+// RegisterLinkSink makes outType selectable by OptOUTTYPE, replacing the
+// sink registered before.
+func RegisterLinkSink(outType string, factory LinkSinkFactory) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks[outType] = factory
+}
+
+// LinkSinkTypes returns the registered output types.
+func LinkSinkTypes() (types []string) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for t := range sinks {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func NewLinkSink(w io.Writer, outType string) (LinkSink, error) {
+	sinksMu.RLock()
+	factory, ok := sinks[outType]
+	sinksMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("not supported type:%s", outType)
+	}
+	return factory(w), nil
+}
+
+// NewStdoutSink writes to stdout, which is left open on Close.
+func NewStdoutSink(outType string) (LinkSink, error) {
+	return NewLinkSink(struct{ io.Writer }{os.Stdout}, outType)
+}
+
+// NewFileSink writes to a new file named by MakeOutFilename, or to stdout if
+// outfile is OutSTDOUT.
+func NewFileSink(outfile, outType string) (sink LinkSink, filename string, err error) {
+	if outfile == OutSTDOUT {
+		sink, err = NewStdoutSink(outType)
+		return sink, OutSTDOUT, err
+	}
+	filename = MakeOutFilename(outfile, outType)
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open output file:%s:%v", filename, err)
+	}
+	sink, err = NewLinkSink(f, outType)
+	if err != nil {
+		f.Close()
+		os.Remove(filename)
+		return nil, "", err
+	}
+	return sink, filename, nil
+}
+
+// linkStream writes links to the sink as found, so the rows are snapshots
+// at that time. Output writes the links left after streaming, and an update
+// row of each link changed since written, e.g. by its response or seen
+// again, so that the last row of a link is final.
+type linkStream struct {
+	mu      sync.Mutex
+	sink    LinkSink
+	written map[Link]LinkInfo
+}
+
+func newLinkStream(sink LinkSink) *linkStream {
+	return &linkStream{sink: sink, written: make(map[Link]LinkInfo)}
+}
+
+func (s *linkStream) write(link Link, info *LinkInfo) error {
+	if s == nil {
+		return nil
+	}
+	if info == nil {
+		info = &LinkInfo{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.written[link]; ok && last == *info {
+		return nil
+	}
+	s.written[link] = *info
+	return s.sink.WriteLink(link, info)
+}
+
+func (s *linkStream) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sink.Close()
+}
@@ -0,0 +1,134 @@
+package goscraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type recordSink struct {
+	records []string
+	closed  bool
+}
+
+func (s *recordSink) WriteLink(link Link, info *LinkInfo) error {
+	s.records = append(s.records, fmt.Sprintf("%s %d", link.To.Path, info.StatusCode))
+	return nil
+}
+
+func (s *recordSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestLinkSink(t *testing.T) {
+	testLinks := Links{
+		*ls[1]: {Seq: 1, StatusCode: 200},
+		*ls[0]: {Seq: 2, StatusCode: 404},
+	}
+	sorted, _ := SortLinks(testLinks, OrderSORTED)
+
+	var buf bytes.Buffer
+	sink, err := NewLinkSink(&buf, OptOUTPUTJSON)
+	if err != nil {
+		t.Fatalf("error in NewLinkSink:%v", err)
+	}
+	for _, l := range sorted {
+		sink.WriteLink(l, testLinks[l])
+	}
+	sink.Close()
+	expect, _ := Links2Json(testLinks)
+	if buf.String() != string(expect) {
+		t.Errorf("not matched json,\nwant: %s,\nhave: %s", expect, buf.String())
+	}
+
+	buf.Reset()
+	sink, _ = NewLinkSink(&buf, OptOUTPUTJSONL)
+	for _, l := range sorted {
+		sink.WriteLink(l, testLinks[l])
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("not matched jsonl lines: %v", lines)
+	}
+	record := &LinkRecord{}
+	if err := json.Unmarshal([]byte(lines[1]), record); err != nil || record.StatusCode != 200 || record.To != ls[1].To {
+		t.Errorf("not matched jsonl record: %v, %v", record, err)
+	}
+
+	for outType, expect := range map[string]string{OptOUTPUTCSV: strings.Join(csvHeader, ",") + "\n", OptOUTPUTJSON: "[]"} {
+		buf.Reset()
+		sink, _ = NewLinkSink(&buf, outType)
+		sink.Close()
+		if buf.String() != expect {
+			t.Errorf("not matched empty %s,\nwant: %s,\nhave: %s", outType, expect, buf.String())
+		}
+	}
+	if _, err := NewLinkSink(&buf, "unknown"); err == nil {
+		t.Errorf("no error in unknown type")
+	}
+}
+
+func TestStreamLinks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/logout">logout</a><a href="/a">a</a>`)
+		case "/a":
+			fmt.Fprint(w, `<a href="/b">b</a>`)
+		case "/b":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	safety, _ := NewSafetyGuard(DefaultSafetyPresets, nil)
+	sink := &recordSink{}
+	lsc, err := NewLinkScraper(&Config{
+		Logger: logger,
+		Entry:  ts.URL + "/",
+		Sink:   sink,
+		Stream: true,
+		Safety: safety,
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if err := lsc.Scrape(); err != nil {
+		t.Errorf("error in Scrape:%v", err)
+	}
+	// snapshots as found, and updates by the responses and seen counts
+	expect := "[/a 0 /b 0 /logout 0 /a 200 /b 404]"
+	if fmt.Sprint(sink.records) != expect || !sink.closed {
+		t.Errorf("not matched stream,\nwant: %v,\nhave: %v", expect, sink.records)
+	}
+
+	var buf bytes.Buffer
+	lsc, err = NewLinkScraper(&Config{
+		Logger: logger,
+		Entry:  ts.URL + "/",
+		Sink:   NewJsonlSink(&buf),
+		Stream: true,
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if err := lsc.Scrape(); err != nil {
+		t.Errorf("error in Scrape:%v", err)
+	}
+	links, err := ReadLinksJSON(&buf)
+	if err != nil {
+		t.Fatalf("error in ReadLinksJSON:%v", err)
+	}
+	for l, info := range lsc.Links {
+		have := links[l]
+		if have == nil || have.StatusCode != info.StatusCode || have.SeenCount != info.SeenCount || !have.LastSeen.Equal(info.LastSeen) {
+			t.Errorf("not final record of %s,\nwant: %v,\nhave: %v", l.To.Path, info, have)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package goscraper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly"
+	"github.com/gocolly/colly/storage"
+)
+
+const (
+	StateLINK    = "link"
+	StateQUEUE   = "queue"
+	StateDONE    = "done"
+	StateCOOKIES = "cookies"
+)
+
+// StateRecord is a line of the state file. Later records of the same link or
+// request overwrite earlier ones.
+type StateRecord struct {
+	Type    string        `json:"type"`
+	Link    *LinkRecord   `json:"link,omitempty"`
+	Request *StateRequest `json:"request,omitempty"`
+	Result  *LinkInfo     `json:"result,omitempty"`
+	URL     string        `json:"url,omitempty"`
+	Cookies string        `json:"cookies,omitempty"`
+}
+
+// StateRequest is a request in the frontier, a visit of URL or a form
+// submission.
+type StateRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Depth  int             `json:"depth"`
+	Form   *FormSubmission `json:"form,omitempty"`
+}
+
+func (r *StateRequest) key() string {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return r.Method + " " + r.URL
+	}
+	return r.Method + " " + targetKey(u)
+}
+
+// context key of the depth of a resumed request, set to the request by
+// LinkScraper
+const ctxDEPTH = "goscraper.depth"
+
+// Do sends the request from the collector at its saved depth, so that links
+// beyond MaxDepth of c are not visited on resume.
+func (r *StateRequest) Do(c *colly.Collector) error {
+	if c.MaxDepth > 0 && r.Depth > c.MaxDepth {
+		return colly.ErrMaxDepth
+	}
+	ctx := colly.NewContext()
+	ctx.Put(ctxDEPTH, strconv.Itoa(r.Depth))
+	if r.Form != nil {
+		req, err := r.Form.NewRequest()
+		if err != nil {
+			return err
+		}
+		var body io.Reader
+		if req.Body != nil {
+			body = req.Body
+		}
+		return c.Request(req.Method, req.URL.String(), body, ctx, req.Header)
+	}
+	return c.Request(r.Method, r.URL, nil, ctx, nil)
+}
+
+// resumeDepth sets the saved depth of a resumed request to r, once as the
+// context is shared with the requests of its links.
+func resumeDepth(r *colly.Request) {
+	if d := r.Ctx.Get(ctxDEPTH); d != "" {
+		if depth, err := strconv.Atoi(d); err == nil {
+			r.Depth = depth
+		}
+		r.Ctx.Put(ctxDEPTH, "")
+	}
+}
+
+// CrawlState appends the progress of a crawl to a JSONL file: found links,
+// queued and finished requests and cookies, so that a killed crawl can be
+// resumed. It is also the colly storage to keep cookies.
+type CrawlState struct {
+	*storage.InMemoryStorage
+	Filename string
+	mu       sync.Mutex
+	file     *os.File
+	enc      *json.Encoder
+	links    Links
+	queued   map[string]*StateRequest
+	order    []string
+	done     map[string]*LinkInfo
+	lastDone map[string]bool
+	cookies  []*StateRecord
+}
+
+// OpenCrawlState opens filename, reading the last run if resume is true or
+// starting over otherwise.
+func OpenCrawlState(filename string, resume bool) (*CrawlState, error) {
+	s := &CrawlState{
+		InMemoryStorage: &storage.InMemoryStorage{},
+		Filename:        filename,
+		links:           make(Links),
+		queued:          make(map[string]*StateRequest),
+		done:            make(map[string]*LinkInfo),
+		lastDone:        make(map[string]bool),
+	}
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	// not readable by others, as it has cookies
+	f, err := os.OpenFile(filename, flag, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state:%s:%v", filename, err)
+	}
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to chmod state:%s:%v", filename, err)
+	}
+	s.file = f
+	s.enc = json.NewEncoder(f)
+	return s, nil
+}
+
+func (s *CrawlState) load() error {
+	f, err := os.Open(s.Filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open state:%s:%v", s.Filename, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var offset int64
+	broken := 0
+	for n := 1; scanner.Scan(); n++ {
+		if broken > 0 {
+			return fmt.Errorf("failed to read state:%s:%d", s.Filename, broken)
+		}
+		rec := &StateRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			// only the last line may be cut by a kill
+			broken = n
+			continue
+		}
+		offset += int64(len(scanner.Bytes())) + 1
+		switch rec.Type {
+		case StateLINK:
+			if rec.Link != nil && rec.Link.LinkInfo != nil {
+				s.links[rec.Link.Link] = rec.Link.LinkInfo
+			}
+		case StateQUEUE:
+			if rec.Request != nil {
+				if _, ok := s.queued[rec.Request.key()]; !ok {
+					s.order = append(s.order, rec.Request.key())
+				}
+				s.queued[rec.Request.key()] = rec.Request
+			}
+		case StateDONE:
+			if rec.Request != nil {
+				s.done[rec.Request.key()] = rec.Result
+				s.lastDone[rec.Request.key()] = true
+			}
+		case StateCOOKIES:
+			s.cookies = append(s.cookies, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read state:%s:%v", s.Filename, err)
+	}
+	if broken > 0 {
+		if err := os.Truncate(s.Filename, offset); err != nil {
+			return fmt.Errorf("failed to truncate state:%s:%v", s.Filename, err)
+		}
+	}
+	return nil
+}
+
+// Init initializes the cookie storage with the cookies of the last run.
+func (s *CrawlState) Init() error {
+	if err := s.InMemoryStorage.Init(); err != nil {
+		return err
+	}
+	for _, rec := range s.cookies {
+		u, err := url.Parse(rec.URL)
+		if err != nil {
+			continue
+		}
+		s.InMemoryStorage.SetCookies(u, rec.Cookies)
+	}
+	return nil
+}
+
+func (s *CrawlState) SetCookies(u *url.URL, cookies string) {
+	s.InMemoryStorage.SetCookies(u, cookies)
+	s.write(&StateRecord{Type: StateCOOKIES, URL: u.String(), Cookies: cookies})
+}
+
+func (s *CrawlState) write(rec *StateRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.enc == nil {
+		return fmt.Errorf("state not opened:%s", s.Filename)
+	}
+	if err := s.enc.Encode(rec); err != nil {
+		return fmt.Errorf("failed to write state:%s:%v", s.Filename, err)
+	}
+	return nil
+}
+
+func (s *CrawlState) SaveLink(link *Link, info *LinkInfo) error {
+	if s == nil {
+		return nil
+	}
+	record := *info
+	return s.write(&StateRecord{Type: StateLINK, Link: &LinkRecord{Link: *link, LinkInfo: &record}})
+}
+
+func (s *CrawlState) Queue(r *StateRequest) error {
+	if s == nil {
+		return nil
+	}
+	return s.write(&StateRecord{Type: StateQUEUE, Request: r})
+}
+
+func (s *CrawlState) Done(r *colly.Request, res *LinkInfo) error {
+	if s == nil {
+		return nil
+	}
+	req := &StateRequest{Method: r.Method, URL: r.URL.String(), Depth: r.Depth}
+	s.mu.Lock()
+	s.done[req.key()] = res
+	s.mu.Unlock()
+	return s.write(&StateRecord{Type: StateDONE, Request: req, Result: res})
+}
+
+// IsDone tells whether method and u were requested by the last run, not to
+// request them again on resume even if the collector allows revisits.
+func (s *CrawlState) IsDone(method string, u *url.URL) bool {
+	if s == nil {
+		return false
+	}
+	return s.lastDone[method+" "+targetKey(u)]
+}
+
+// Links returns the links found by the last run.
+func (s *CrawlState) Links() Links {
+	return s.links
+}
+
+// Results returns the responses of the last run by target URL.
+func (s *CrawlState) Results() map[string]*LinkInfo {
+	results := make(map[string]*LinkInfo)
+	for key, res := range s.done {
+		if res != nil {
+			results[strings.SplitN(key, " ", 2)[1]] = res
+		}
+	}
+	return results
+}
+
+// Pending returns the requests queued but not finished by the last run, in
+// queued order.
+func (s *CrawlState) Pending() (pending []*StateRequest) {
+	for _, key := range s.order {
+		if _, ok := s.done[key]; !ok {
+			pending = append(pending, s.queued[key])
+		}
+	}
+	return pending
+}
+
+// Resumed tells whether the last run left anything to continue.
+func (s *CrawlState) Resumed() bool {
+	return s != nil && (len(s.links) > 0 || len(s.order) > 0)
+}
+
+func (s *CrawlState) Close() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close state:%s:%v", s.Filename, err)
+	}
+	s.enc = nil
+	return nil
+}
+
+func entryRequest(entry string) *StateRequest {
+	return &StateRequest{Method: http.MethodGet, URL: entry, Depth: 1}
+}
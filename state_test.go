@@ -0,0 +1,159 @@
+package goscraper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestCrawlStateResume(t *testing.T) {
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie := ""
+		if c, err := r.Cookie("session"); err == nil {
+			cookie = c.Value
+		}
+		requested = append(requested, r.URL.Path+" "+cookie)
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1"})
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a>`)
+		case "/a":
+			fmt.Fprint(w, `<a href="/c">c</a>`)
+		case "/b":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "goscraper")
+	if err != nil {
+		t.Fatalf("failed to create temp dir:%v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.jsonl")
+
+	crawl := func(resume bool, opts ...func(*colly.Collector)) *LinkScraper {
+		state, err := OpenCrawlState(filename, resume)
+		if err != nil {
+			t.Fatalf("error in OpenCrawlState:%v", err)
+		}
+		defer state.Close()
+		lsc, err := NewLinkScraper(&Config{
+			Collector: colly.NewCollector(opts...),
+			Logger:    logger,
+			Entry:     ts.URL + "/",
+			OutFile:   filepath.Join(dir, "output"),
+			State:     state,
+		})
+		if err != nil {
+			t.Fatalf("error in NewLinkScraper:%v", err)
+		}
+		if err := lsc.Scrape(); err != nil {
+			t.Errorf("error in Scrape:%v", err)
+		}
+		return lsc
+	}
+
+	// stopped before visiting the links of the entry
+	crawl(false, colly.MaxDepth(1))
+	if expect := []string{"/ "}; !reflect.DeepEqual(expect, requested) {
+		t.Errorf("not matched first run,\nwant: %v,\nhave: %v", expect, requested)
+	}
+	if fi, err := os.Stat(filename); err != nil {
+		t.Errorf("failed to stat state:%v", err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("not private state: %v", fi.Mode())
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("failed to open state:%v", err)
+	}
+	fmt.Fprint(f, `{"type":"que`)
+	f.Close()
+
+	requested = nil
+	lsc := crawl(true)
+	sort.Strings(requested)
+	if expect := []string{"/a s1", "/b s1", "/c s1"}; !reflect.DeepEqual(expect, requested) {
+		t.Errorf("not matched resumed run,\nwant: %v,\nhave: %v", expect, requested)
+	}
+	status := map[string]int{}
+	for link, info := range lsc.Links {
+		status[link.To.Path] = info.StatusCode
+	}
+	if expect := map[string]int{"/a": 200, "/b": 404, "/c": 200}; !reflect.DeepEqual(expect, status) {
+		t.Errorf("not matched links,\nwant: %v,\nhave: %v", expect, status)
+	}
+	if broken := lsc.BrokenLinks(); len(broken) != 1 {
+		t.Errorf("not matched broken links: %v", broken)
+	}
+
+	requested = nil
+	crawl(true)
+	if len(requested) != 0 {
+		t.Errorf("requested finished crawl: %v", requested)
+	}
+}
+
+func TestCrawlStateResumeDepth(t *testing.T) {
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Content-Type", "text/html")
+		next := map[string]string{"/": "/a", "/a": "/b", "/b": "/c", "/c": "/d"}[r.URL.Path]
+		if next != "" {
+			fmt.Fprintf(w, `<a href="%s">next</a>`, next)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "goscraper")
+	if err != nil {
+		t.Fatalf("failed to create temp dir:%v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.jsonl")
+
+	crawl := func(resume bool, maxDepth int) {
+		state, err := OpenCrawlState(filename, resume)
+		if err != nil {
+			t.Fatalf("error in OpenCrawlState:%v", err)
+		}
+		defer state.Close()
+		lsc, err := NewLinkScraper(&Config{
+			Collector: colly.NewCollector(colly.MaxDepth(maxDepth)),
+			Logger:    logger,
+			Entry:     ts.URL + "/",
+			Sink:      &recordSink{},
+			State:     state,
+		})
+		if err != nil {
+			t.Fatalf("error in NewLinkScraper:%v", err)
+		}
+		if err := lsc.Scrape(); err != nil {
+			t.Errorf("error in Scrape:%v", err)
+		}
+	}
+
+	// /b at depth 3 is left in the state
+	crawl(false, 2)
+	if expect := []string{"/", "/a"}; !reflect.DeepEqual(expect, requested) {
+		t.Errorf("not matched first run,\nwant: %v,\nhave: %v", expect, requested)
+	}
+	requested = nil
+	crawl(true, 3)
+	if expect := []string{"/b"}; !reflect.DeepEqual(expect, requested) {
+		t.Errorf("not matched resumed run,\nwant: %v,\nhave: %v", expect, requested)
+	}
+}
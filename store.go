@@ -0,0 +1,88 @@
+package goscraper
+
+import (
+	"sync"
+)
+
+// LinkStore keeps the links of a crawl, safe for concurrent use by colly
+// callbacks. LinkInfo is only changed through Update, and Get and Links
+// return copies.
+type LinkStore interface {
+	// Add adds link, or counts it as seen again if found. ok is true if link
+	// is new.
+	Add(link *Link) (ok bool)
+	// Put sets info of link, e.g. restored from an earlier run.
+	Put(link Link, info *LinkInfo)
+	Get(link Link) (info LinkInfo, ok bool)
+	// Update calls f with info of link, if found.
+	Update(link Link, f func(info *LinkInfo)) (ok bool)
+	Len() int
+	Links() Links
+}
+
+// MapLinkStore is a LinkStore of Links guarded by a mutex.
+type MapLinkStore struct {
+	mu    sync.RWMutex
+	links Links
+}
+
+func NewMapLinkStore(links Links) *MapLinkStore {
+	if links == nil {
+		links = make(Links)
+	}
+	return &MapLinkStore{links: links}
+}
+
+func (s *MapLinkStore) Add(link *Link) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := Add(s.links, link)
+	return ok
+}
+
+func (s *MapLinkStore) Put(link Link, info *LinkInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links[link] = info
+}
+
+func (s *MapLinkStore) Get(link Link) (LinkInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info, ok := s.links[link]
+	if !ok || info == nil {
+		return LinkInfo{}, ok
+	}
+	return *info, true
+}
+
+func (s *MapLinkStore) Update(link Link, f func(info *LinkInfo)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.links[link]
+	if !ok || info == nil {
+		return false
+	}
+	f(info)
+	return true
+}
+
+func (s *MapLinkStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.links)
+}
+
+func (s *MapLinkStore) Links() Links {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	links := make(Links, len(s.links))
+	for l, info := range s.links {
+		if info != nil {
+			c := *info
+			info = &c
+		}
+		links[l] = info
+	}
+	return links
+}
@@ -0,0 +1,123 @@
+package goscraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMapLinkStore(t *testing.T) {
+	store := NewMapLinkStore(nil)
+	from, _ := url.Parse("http://example.com/")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				to, _ := url.Parse(fmt.Sprintf("http://example.com/%d", j))
+				link := Link{From: *from, To: *to}
+				store.Add(&link)
+				store.Update(link, func(info *LinkInfo) { info.Depth = i })
+				store.Get(link)
+				store.Links()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	links := store.Links()
+	if len(links) != 50 || store.Len() != 50 {
+		t.Fatalf("not matched links: %d", len(links))
+	}
+	seqs := []int{}
+	for _, info := range links {
+		if info.SeenCount != 8 {
+			t.Errorf("not counted seen: %v", info)
+		}
+		seqs = append(seqs, info.Seq)
+	}
+	sort.Ints(seqs)
+	for i, seq := range seqs {
+		if seq != i+1 {
+			t.Fatalf("not unique seq: %v", seqs)
+		}
+	}
+
+	l := Link{From: *from}
+	if store.Update(l, func(*LinkInfo) {}) {
+		t.Errorf("updated not found link")
+	}
+	for l := range links {
+		links[l].Depth = -1
+		if info, _ := store.Get(l); info.Depth == -1 {
+			t.Errorf("not copied links")
+		}
+		break
+	}
+}
+
+func TestParallelScrape(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			for i := 0; i < 10; i++ {
+				fmt.Fprintf(w, `<a href="/p%d">p%d</a>`, i, i)
+			}
+			return
+		}
+		switch {
+		case r.URL.Path == "/p9":
+			w.WriteHeader(http.StatusNotFound)
+		case strings.Count(r.URL.Path, "/") == 1:
+			fmt.Fprintf(w, `<a href="%s/a">a</a><a href="/">top</a>`, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	lsc, err := NewLinkScraper(&Config{
+		Logger:      logger,
+		Entry:       ts.URL + "/",
+		Sink:        NewJsonlSink(&buf),
+		Stream:      true,
+		Parallelism: 4,
+		Delay:       time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("error in NewLinkScraper:%v", err)
+	}
+	if !lsc.Collector.Async {
+		t.Errorf("not async collector")
+	}
+	if err := lsc.Scrape(); err != nil {
+		t.Errorf("error in Scrape:%v", err)
+	}
+	streamed, err := ReadLinksJSON(&buf)
+	if err != nil {
+		t.Fatalf("error in ReadLinksJSON:%v", err)
+	}
+	// 10 from top, and 2 from each page but p9
+	if len(lsc.Links) != 28 || len(streamed) != 28 {
+		t.Errorf("not matched links: %d, %d", len(lsc.Links), len(streamed))
+	}
+	for l, info := range lsc.Links {
+		if l.From.Path == "/" && l.To.Path != "/p9" && info.StatusCode != http.StatusOK {
+			t.Errorf("not tracked status: %v %v", l.To.String(), info)
+		}
+		if l.To.Path == "/p9" && info.StatusCode != http.StatusNotFound {
+			t.Errorf("not tracked error: %v", info)
+		}
+	}
+	if len(lsc.BrokenLinks()) != 1 {
+		t.Errorf("not matched broken links: %v", lsc.BrokenLinks())
+	}
+}
@@ -0,0 +1,98 @@
+package goscraper
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// targetTracker copies the response of each visited URL into the LinkInfo in
+// store of every link pointing to it, including links found after the visit.
+type targetTracker struct {
+	mu      sync.Mutex
+	store   LinkStore
+	started map[*colly.Request]time.Time
+	results map[string]*LinkInfo
+	links   map[string][]Link
+	retries map[string]int
+}
+
+func newTargetTracker(store LinkStore) *targetTracker {
+	return &targetTracker{
+		store:   store,
+		started: make(map[*colly.Request]time.Time),
+		results: make(map[string]*LinkInfo),
+		links:   make(map[string][]Link),
+		retries: make(map[string]int),
+	}
+}
+
+func targetKey(u *url.URL) string {
+	t := *u
+	t.Fragment = ""
+	return t.String()
+}
+
+func (t *targetTracker) start(r *colly.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started[r] = time.Now()
+}
+
+func (t *targetTracker) finish(r *colly.Response, err error) *LinkInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := &LinkInfo{StatusCode: r.StatusCode}
+	if start, ok := t.started[r.Request]; ok {
+		res.ResponseTime = time.Since(start)
+		delete(t.started, r.Request)
+	}
+	if r.Headers != nil {
+		res.ContentType = r.Headers.Get("Content-Type")
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	res.Outcome, res.ErrorClass = outcome(r.StatusCode, err)
+	key := targetKey(r.Request.URL)
+	res.Attempts = t.retries[key] + 1
+	t.results[key] = res
+	for _, l := range t.links[key] {
+		t.store.Update(l, func(info *LinkInfo) { info.setTarget(res) })
+	}
+	return res
+}
+
+// retry counts a retry of r, made after finish.
+func (t *targetTracker) retry(r *colly.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.retries[targetKey(r.URL)]++
+}
+
+func (t *targetTracker) result(u *url.URL) *LinkInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.results[targetKey(u)]
+}
+
+// restore sets the result of key from an earlier run.
+func (t *targetTracker) restore(key string, res *LinkInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.results[key] = res
+}
+
+// track copies the response of target, the URL requested for link, into
+// link. target is not link.To, which is canonical.
+func (t *targetTracker) track(link *Link, target *url.URL) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := targetKey(target)
+	t.links[key] = append(t.links[key], *link)
+	if res, ok := t.results[key]; ok {
+		t.store.Update(*link, func(info *LinkInfo) { info.setTarget(res) })
+	}
+}
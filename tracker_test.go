@@ -0,0 +1,42 @@
+package goscraper
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestTargetTracker(t *testing.T) {
+	store := NewMapLinkStore(nil)
+	tracker := newTargetTracker(store)
+	to, _ := url.Parse("http://example.com/a#top")
+	u, _ := url.Parse("http://example.com/a")
+	req := &colly.Request{URL: u}
+
+	before := Link{To: *to}
+	store.Put(before, &LinkInfo{})
+	tracker.track(&before, &before.To)
+	tracker.start(req)
+	tracker.finish(&colly.Response{
+		Request:    req,
+		StatusCode: http.StatusNotFound,
+		Headers:    &http.Header{"Content-Type": []string{"text/html"}},
+	}, errors.New("Not Found"))
+
+	after := Link{To: *u}
+	store.Put(after, &LinkInfo{})
+	tracker.track(&after, &after.To)
+
+	for _, l := range []Link{before, after} {
+		info, _ := store.Get(l)
+		if info.StatusCode != http.StatusNotFound || info.ContentType != "text/html" || info.Error != "Not Found" {
+			t.Errorf("not recorded response: %v", info)
+		}
+	}
+	if len(tracker.started) != 0 {
+		t.Errorf("not finished request: %v", tracker.started)
+	}
+}